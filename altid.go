@@ -0,0 +1,162 @@
+package go_vcard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+group the properties of k by ALTID,the properties of a group are alternative
+representations of the same value,e.g. FN in several languages.a property
+without ALTID is a group by itself,groups are in the order of their first
+property
+ */
+func (c Card) AltIDGroups(k string) [][]*Property {
+	var groups [][]*Property
+	index := make(map[string]int)
+	for _,p := range c[k]{
+		_,altid := findParam(p.Params,ParamAltid)
+		if altid == ""{
+			groups = append(groups,[]*Property{p})
+			continue
+		}
+		if i,ok := index[altid];ok{
+			groups[i] = append(groups[i],p)
+			continue
+		}
+		index[altid] = len(groups)
+		groups = append(groups,[]*Property{p})
+	}
+	return groups
+}
+
+/*
+the number of instances of k,an ALTID group count as one
+ */
+func (c Card) Count(k string) int {
+	return len(c.AltIDGroups(k))
+}
+
+/*
+check the cardinality of RFC 6350:exactly one VERSION,at least one FN and at
+most one of KIND,N,BDAY,ANNIVERSARY,GENDER,PRODID,REV and UID,an ALTID group
+count as one instance
+ */
+func (c Card) CheckCardinality() error {
+	var errs []string
+	if n := len(c[PropVersion]);n != 1{
+		errs = append(errs,fmt.Sprintf("%s must occur once,got %d",PropVersion,n))
+	}
+	if c.Count(PropFN) < 1{
+		errs = append(errs,PropFN+" must occur at least once")
+	}
+	var single []string
+	for k := range singleProps{
+		single = append(single,k)
+	}
+	sort.Strings(single)
+	for _,k := range single{
+		if n := c.Count(k);n > 1{
+			errs = append(errs,fmt.Sprintf("%s must occur at most once,got %d",k,n))
+		}
+	}
+	if len(errs) > 0{
+		return fmt.Errorf("vcard:cardinality:%s",strings.Join(errs,";"))
+	}
+	return nil
+}
+
+/*
+lower case a BCP 47 tag,'_' is accepted as separator,e.g. ja_JP to ja-jp
+ */
+func canonicalTag(tag string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(tag),"_","-",-1))
+}
+
+/*
+how well an available tag match a wanted tag,0 is no match:
+4 the same tag,3 available is a prefix of wanted(ja for ja-JP),
+2 wanted is a prefix of available(ja-JP for ja),1 the same language(en-GB
+for en-US)
+ */
+func matchTag(wanted,available string) int {
+	w,a := canonicalTag(wanted),canonicalTag(available)
+	if w == "" || a == ""{
+		return 0
+	}
+	switch {
+	case w == a:
+		return 4
+	case strings.HasPrefix(w,a+"-"):
+		return 3
+	case strings.HasPrefix(a,w+"-"):
+		return 2
+	case strings.SplitN(w,"-",2)[0] == strings.SplitN(a,"-",2)[0]:
+		return 1
+	}
+	return 0
+}
+
+/*
+return the property of k in the best matching language,langs are BCP 47 tags
+in the order of preference,e.g. "ja-JP","en".the ALTID group of the
+preferred property is chosen first,then the language of a property within
+the group is its LANGUAGE param.without match the property of the group
+without LANGUAGE is returned,then the preferred property
+ */
+func (c Card) PropertyFor(k string,langs ...string) *Property {
+	pref := c.Pref(k)
+	if pref == nil{
+		return nil
+	}
+	var props []*Property
+	for _,g := range c.AltIDGroups(k){
+		for _,p := range g{
+			if p == pref{
+				props = g
+			}
+		}
+	}
+	for _,lang := range langs{
+		var best *Property
+		score := 0
+		for _,p := range props{
+			_,tag := findParam(p.Params,ParamLanguage)
+			if s := matchTag(lang,tag);s > score{
+				best,score = p,s
+			}
+		}
+		if best != nil{
+			return best
+		}
+	}
+	if lk,_ := findParam(pref.Params,ParamLanguage);lk == ""{
+		return pref
+	}
+	//the default of an ALTID group has no LANGUAGE
+	for _,p := range props{
+		if lk,_ := findParam(p.Params,ParamLanguage);lk == ""{
+			return p
+		}
+	}
+	return pref
+}
+
+/*
+return the FN in the best matching language,see PropertyFor
+ */
+func (c Card) FormattedNameFor(langs ...string) *Property {
+	return c.PropertyFor(PropFN,langs...)
+}
+
+/*
+return the N in the best matching language,see PropertyFor
+ */
+func (c Card) NameFor(langs ...string) *Name {
+	p := c.PropertyFor(PropN,langs...)
+	if p == nil{
+		return nil
+	}
+	return newName(p)
+}
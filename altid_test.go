@@ -0,0 +1,119 @@
+package go_vcard
+
+import (
+	"strings"
+	"testing"
+)
+
+var testCardMultilingual = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN;ALTID=1;LANGUAGE=ja:山田太郎\r\n" +
+	"FN;ALTID=1;LANGUAGE=en:Taro Yamada\r\n" +
+	"FN;ALTID=1:Yamada Taro\r\n" +
+	"N;ALTID=1;LANGUAGE=ja-Jpan:山田;太郎;;;\r\n" +
+	"N;ALTID=1;LANGUAGE=en:Yamada;Taro;;;\r\n" +
+	"END:VCARD\r\n"
+
+func TestCard_FormattedNameFor(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader(testCardMultilingual)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		langs    []string
+		expected string
+	}{
+		{[]string{"ja"}, "山田太郎"},
+		{[]string{"ja-JP"}, "山田太郎"},
+		{[]string{"en-GB"}, "Taro Yamada"},
+		{[]string{"EN_us"}, "Taro Yamada"},
+		{[]string{"fr", "en"}, "Taro Yamada"},
+		{[]string{"fr"}, "Yamada Taro"},
+		{nil, "Yamada Taro"},
+	}
+	for _, test := range tests {
+		if fn := card.FormattedNameFor(test.langs...); fn.GetValueFirstText() != test.expected {
+			t.Errorf("FormattedNameFor(%v) = %q, want %q", test.langs, fn.GetValueFirstText(), test.expected)
+		}
+	}
+
+	if n := card.NameFor("ja-JP"); n.FamilyName != "山田" || n.GivenName != "太郎" {
+		t.Errorf("Unexpected Japanese name %+v", n)
+	}
+	if n := card.NameFor("en"); n.FamilyName != "Yamada" {
+		t.Errorf("Unexpected English name %+v", n)
+	}
+	if n := (Card{}).NameFor("en"); n != nil {
+		t.Errorf("Expected no name for an empty card, got %+v", n)
+	}
+}
+
+func TestCard_PropertyFor_altIDGroups(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\nVERSION:4.0\r\n" +
+		"TITLE;ALTID=1;LANGUAGE=en:Engineer\r\n" +
+		"TITLE;ALTID=1;LANGUAGE=fr:Ingénieur\r\n" +
+		"TITLE;ALTID=2;PREF=1;LANGUAGE=en:Manager\r\n" +
+		"TITLE;ALTID=2;PREF=1;LANGUAGE=ja:マネージャー\r\n" +
+		"FN:Jean Dupont\r\n" +
+		"END:VCARD\r\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		langs    []string
+		expected string
+	}{
+		{[]string{"en"}, "Manager"},
+		{[]string{"ja"}, "マネージャー"},
+		//fr is only in the group that is not preferred
+		{[]string{"fr", "ja"}, "マネージャー"},
+		{[]string{"fr"}, "Manager"},
+		{nil, "Manager"},
+	}
+	for _, test := range tests {
+		if p := card.PropertyFor(PropTiTle, test.langs...); p.GetValueFirstText() != test.expected {
+			t.Errorf("PropertyFor(%v) = %q, want %q", test.langs, p.GetValueFirstText(), test.expected)
+		}
+	}
+
+	//without PREF the first group is preferred
+	for _, p := range card[PropTiTle] {
+		delete(p.Params, ParamPref)
+	}
+	if p := card.PropertyFor(PropTiTle, "ja", "fr"); p.GetValueFirstText() != "Ingénieur" {
+		t.Errorf("Expected the fr title of the first group, got %q", p.GetValueFirstText())
+	}
+}
+
+func TestCard_CheckCardinality(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader(testCardMultilingual)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if groups := card.AltIDGroups(PropFN); len(groups) != 1 || len(groups[0]) != 3 {
+		t.Errorf("Expected a single ALTID group of 3 FN, got %v", groups)
+	}
+	if n := card.Count(PropN); n != 1 {
+		t.Errorf("Expected N to count once, got %d", n)
+	}
+	if err := card.CheckCardinality(); err != nil {
+		t.Error("Expected no cardinality error, got:", err)
+	}
+
+	card.Add(PropN, &Property{Name: PropN, Value: [][]string{{"Doe"}, {"John"}, {""}, {""}, {""}}})
+	card.Add(PropUid, &Property{Name: PropUid, Value: [][]string{{"urn:uuid:1"}}, Params: map[string][]string{ParamAltid: {"1"}}})
+	card.Add(PropUid, &Property{Name: PropUid, Value: [][]string{{"urn:uuid:2"}}, Params: map[string][]string{ParamAltid: {"2"}}})
+	delete(card, PropFN)
+	err = card.CheckCardinality()
+	if err == nil {
+		t.Fatal("Expected a cardinality error")
+	}
+	for _, s := range []string{"FN must occur at least once", "N must occur at most once,got 2", "UID must occur at most once,got 2"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Expected %q in %q", s, err.Error())
+		}
+	}
+}
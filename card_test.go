@@ -1,6 +1,7 @@
 package go_vcard
 
 import (
+	"bytes"
 	"reflect"
 	"strings"
 	"testing"
@@ -233,6 +234,44 @@ func TestCard_Name(t *testing.T) {
 	}
 }
 
+func TestCard_NameComponents(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\nVERSION:4.0\r\nFN:John Doe\r\n" +
+		"N:Doe;John;;Mr.,Dr.\r\nADR:;;1 Main St;Springfield\r\nEND:VCARD\r\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//missing components are empty,lists are joined
+	name := card.Name()
+	if name.FamilyName != "Doe" || name.GivenName != "John" || name.HonorificPrefix != "Mr.,Dr." || name.HonorificSuffix != "" {
+		t.Errorf("Unexpected name %+v", name)
+	}
+	address := card.Address()
+	if address.StreetAddress != "1 Main St" || address.Locality != "Springfield" || address.Country != "" {
+		t.Errorf("Unexpected address %+v", address)
+	}
+
+	//one component per item of Value,the old value is replaced
+	name.GivenName, name.HonorificPrefix = "Johnny", "Mr."
+	expected := [][]string{{"Doe"}, {"Johnny"}, {""}, {"Mr."}, {""}}
+	if p := name.property(); !reflect.DeepEqual(p.Value, expected) {
+		t.Errorf("Expected N value %q but got %q", expected, p.Value)
+	}
+	address.Country = "USA"
+	expected = [][]string{{""}, {""}, {"1 Main St"}, {"Springfield"}, {""}, {""}, {"USA"}}
+	if p := address.property(); !reflect.DeepEqual(p.Value, expected) {
+		t.Errorf("Expected ADR value %q but got %q", expected, p.Value)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, "\r\nN:Doe;Johnny;;Mr.;\r\n") || !strings.Contains(s, "\r\nADR:;;1 Main St;Springfield;;;USA\r\n") {
+		t.Errorf("Unexpected encoded card %q", s)
+	}
+}
+
 func TestCard_Kind(t *testing.T) {
 	card := make(Card)
 	card.SetKind(KindIndividual)
@@ -336,3 +375,36 @@ func TestCard_Revision(t *testing.T) {
 	}
 }
 
+
+func TestCard_NameAndAddressLists(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\nVERSION:4.0\r\nFN:John Doe\r\n" +
+		"N:Doe;John;;Mr.,Dr.\r\nADR:;;1 Main St,Apt 2;Springfield\r\nEND:VCARD\r\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//missing components are empty
+	name := card.Name()
+	if name.FamilyName != "Doe" || name.HonorificPrefix != "Mr.,Dr." || name.HonorificSuffix != "" {
+		t.Errorf("Unexpected name %+v", name)
+	}
+	address := card.Address()
+	if address.StreetAddress != "1 Main St,Apt 2" || address.Country != "" {
+		t.Errorf("Unexpected address %+v", address)
+	}
+
+	//the lists are kept when the value is written back
+	c := Card{PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}}}
+	c.AddName(&Name{FamilyName: name.FamilyName, GivenName: name.GivenName, HonorificPrefix: name.HonorificPrefix})
+	c.AddAdress(&Address{StreetAddress: address.StreetAddress, Locality: address.Locality})
+	if v := c.Get(PropN).Value; !reflect.DeepEqual(v, [][]string{{"Doe"}, {"John"}, {""}, {"Mr.", "Dr."}, {""}}) {
+		t.Errorf("Unexpected N value %q", v)
+	}
+	var b strings.Builder
+	if err := NewEncoder(&b).Encode(c); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, "\r\nN:Doe;John;;Mr.,Dr.;\r\n") || !strings.Contains(s, "\r\nADR:;;1 Main St,Apt 2;Springfield;;;\r\n") {
+		t.Errorf("Unexpected encoded card %q", s)
+	}
+}
@@ -0,0 +1,507 @@
+package go_vcard
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	propLabel = "LABEL"
+	propAgent = "AGENT"
+	propSortString = "SORT-STRING"
+
+	paramLabel = "LABEL"
+)
+
+/*
+4.0 only properties and the name they get in vCard 3.0,
+an empty name means the property is dropped
+ */
+var v3PropNames = map[string]string{
+	PropKind: "X-ADDRESSBOOKSERVER-KIND",
+	PropMember: "X-ADDRESSBOOKSERVER-MEMBER",
+	PropGender: "X-GENDER",
+	PropAnniversary: "X-ANNIVERSARY",
+	PropRelated: "X-RELATED",
+	PropLang: "X-LANG",
+	PropXML: "",
+	PropClientPidmap: "",
+}
+
+func (c Card) version() (string,error) {
+	vers := c.Value(PropVersion)
+	if len(vers) < 1 || len(vers[0]) < 1{
+		return "",fmt.Errorf("vcard:VCARD must have version infomation")
+	}
+	return vers[0][0],nil
+}
+
+/*
+convert a vCard 2.1 or 3.0 card to 4.0 in place,
+return the lossy steps of the conversion as warnings
+ */
+func (c Card) ToV4() (warnings []string,err error) {
+	ver,err := c.version()
+	if err != nil{
+		return nil,err
+	}
+	if strings.HasPrefix(ver,"4."){
+		return nil,nil
+	}
+	c.Set(PropVersion,&Property{Name:PropVersion,Value:[][]string{{"4.0"}}})
+	for k,props := range c{
+		if strings.EqualFold(k,PropVersion){
+			continue
+		}
+		for _,p := range props{
+			if p.IsHasType("pref"){
+				removeType(p,"pref")
+				p.SetParam(ParamPref,"1")
+			}
+			switch strings.ToUpper(k) {
+			case PropPhoto,PropLogo,PropSound,PropKey:
+				v4Media(p)
+			case PropBday:
+				p.Value = [][]string{{v4Date(p.GetValueFirstText())}}
+			}
+		}
+	}
+
+	for _,l := range c.popProps(propLabel){
+		adr := c.labelAddress(l)
+		if adr == nil{
+			adr = &Property{Group:l.Group,Name:PropAdr,Value:[][]string{{""},{""},{""},{""},{""},{""},{""}}}
+			if types := l.Params[ParamType];types != nil{
+				adr.Params = map[string][]string{ParamType:append([]string(nil),types...)}
+			}
+			c.Add(PropAdr,adr)
+		}
+		adr.SetParam(paramLabel,joinValue(l.Value))
+	}
+
+	for _,a := range c.popProps(propAgent){
+		r := &Property{Group:a.Group,Name:PropRelated,Params:map[string][]string{ParamType:{"agent"}}}
+		if _,v := findParam(a.Params,ParamValue);strings.EqualFold(v,"uri") || strings.EqualFold(v,"url"){
+			r.Value = a.Value
+		}else if uid,fn := agentCard(joinValue(a.Value));uid != ""{
+			r.Value = [][]string{{uid}}
+			warnings = append(warnings,"AGENT:embedded vCard replaced by its UID")
+		}else{
+			r.Params[ParamValue] = []string{"text"}
+			r.Value = [][]string{{fn}}
+			warnings = append(warnings,"AGENT:embedded vCard replaced by its FN")
+		}
+		c.Add(PropRelated,r)
+	}
+
+	if uids := c.popProps("X-ABUID");len(uids) > 0{
+		if c.Get(PropUid) == nil{
+			uid := strings.TrimSuffix(uids[0].GetValueFirstText(),":ABPerson")
+			c.SetValue(PropUid,[][]string{{"urn:uuid:"+strings.ToLower(uid)}})
+		}else{
+			c[uids[0].Name] = uids
+		}
+	}
+
+	if anns := c.popProps("X-ANNIVERSARY");len(anns) > 0{
+		if c.Get(PropAnniversary) == nil{
+			c.SetValue(PropAnniversary,[][]string{{v4Date(anns[0].GetValueFirstText())}})
+		}
+		if len(anns) > 1{
+			warnings = append(warnings,"X-ANNIVERSARY:only the first one is kept")
+		}
+	}
+
+	//undo the x-names of ToV3
+	for _,name := range []string{PropKind,PropMember,PropRelated,PropLang}{
+		props := c.popProps(v3PropNames[name])
+		if len(props) == 0{
+			continue
+		}
+		if name == PropKind && c.Get(PropKind) != nil{
+			warnings = append(warnings,v3PropNames[name]+":dropped,KIND is present")
+			continue
+		}
+		for _,p := range props{
+			p.Name = name
+			if name == PropKind{
+				p.Value = [][]string{{strings.ToLower(p.GetValueFirstText())}}
+			}
+		}
+		c[name] = append(c[name],props...)
+	}
+
+	if sorts := c.popProps(propSortString);len(sorts) > 0{
+		if n := c.Get(PropN);n != nil{
+			if sk,_ := findParam(n.Params,ParamSortAs);sk != ""{
+				delete(n.Params,sk)
+			}
+			for _,v := range strings.Split(joinValue(sorts[0].Value),","){
+				n.AddParam(ParamSortAs,v)
+			}
+		}else{
+			warnings = append(warnings,"SORT-STRING:dropped,the card has no N")
+		}
+	}
+
+	if gens := c.popProps("X-GENDER");len(gens) > 0{
+		if c.Get(PropGender) == nil{
+			switch gen := gens[0].GetValueFirstText();strings.ToLower(gen) {
+			case "male","m":
+				c.SetGender(SexMale,"")
+			case "female","f":
+				c.SetGender(SexFemale,"")
+			default:
+				c.SetGender(SexOther,gen)
+			}
+		}
+	}
+	return warnings,nil
+}
+
+/*
+convert a vCard 2.1 or 4.0 card to 3.0 in place,4.0 only properties are
+x-prefixed or dropped,return the lossy steps of the conversion as warnings
+ */
+func (c Card) ToV3() (warnings []string,err error) {
+	ver,err := c.version()
+	if err != nil{
+		return nil,err
+	}
+	if strings.HasPrefix(ver,"3."){
+		return nil,nil
+	}
+	c.Set(PropVersion,&Property{Name:PropVersion,Value:[][]string{{"3.0"}}})
+	if !strings.HasPrefix(ver,"4."){
+		//vCard 2.1,only inline media differ once decoded
+		for k,props := range c{
+			switch strings.ToUpper(k) {
+			case PropPhoto,PropLogo,PropSound,PropKey:
+				for _,p := range props{
+					legacyMedia(p,"3.0")
+				}
+			}
+		}
+		return nil,nil
+	}
+
+	warned := make(map[string]bool)
+	warn := func(w string) {
+		if !warned[w]{
+			warned[w] = true
+			warnings = append(warnings,w)
+		}
+	}
+
+	var keys []string
+	for k := range c{
+		keys = append(keys,k)
+	}
+	for _,k := range keys{
+		name := strings.ToUpper(k)
+		if name == PropVersion{
+			continue
+		}
+		props := c[k]
+		pref := c.Pref(k)
+		prefs := 0
+		for _,p := range props{
+			if p.GetFirstParamVal(ParamPref) != ""{
+				prefs++
+			}
+		}
+		if prefs > 1{
+			warn(name+":PREF order reduced to TYPE=pref")
+		}
+		var added []*Property
+		for _,p := range props{
+			if pk,_ := findParam(p.Params,ParamPref);pk != ""{
+				delete(p.Params,pk)
+				if p == pref && !p.IsHasType("pref"){
+					p.AddParam(ParamType,"pref")
+				}
+			}
+			for pk := range p.Params{
+				switch strings.ToUpper(pk) {
+				case ParamPid:
+					warn(name+":PID param dropped")
+				case ParamAltid:
+					warn(name+":ALTID param dropped")
+				case ParamCalscale:
+					if !strings.EqualFold(p.GetFirstParamVal(pk),"gregorian"){
+						warn(name+":CALSCALE param dropped")
+					}
+				case ParamSortAs:
+					if name == PropN{
+						added = append(added,&Property{Group:p.Group,Name:propSortString,Value:[][]string{{strings.Join(p.Params[pk],",")}}})
+					}else{
+						warn(name+":SORT-AS param dropped")
+					}
+				case ParamGEO,ParamTZ:
+					warn(name+":"+strings.ToUpper(pk)+" param dropped")
+				default:
+					continue
+				}
+				delete(p.Params,pk)
+			}
+			switch name {
+			case PropPhoto,PropLogo,PropSound,PropKey:
+				legacyMedia(p,"3.0")
+			case PropAdr:
+				if lp := labelProperty(p);lp != nil{
+					added = append(added,lp)
+				}
+			case PropGender:
+				switch p.GetValueFirstText() {
+				case SexMale:
+					p.Value = [][]string{{"Male"}}
+				case SexFemale:
+					p.Value = [][]string{{"Female"}}
+				}
+			}
+			if mk,_ := findParam(p.Params,ParamMediatype);mk != ""{
+				delete(p.Params,mk)
+				warn(name+":MEDIATYPE param dropped")
+			}
+		}
+		for _,a := range added{
+			c.Add(a.Name,a)
+		}
+
+		if name == PropRelated{
+			//an agent given by URI is still an AGENT in vCard 3.0
+			var rest []*Property
+			for _,p := range props{
+				if p.IsHasType("agent") && !strings.EqualFold(p.GetFirstParamVal(ParamValue),"text"){
+					removeType(p,"agent")
+					p.Name = propAgent
+					p.SetParam(ParamValue,"uri")
+					c.Add(propAgent,p)
+				}else{
+					rest = append(rest,p)
+				}
+			}
+			props = rest
+			c[k] = rest
+		}
+		v3,ok := v3PropNames[name]
+		if !ok{
+			continue
+		}
+		delete(c,k)
+		if len(props) == 0{
+			continue
+		}
+		if v3 == ""{
+			warn(name+":property dropped")
+			continue
+		}
+		for _,p := range props{
+			p.Name = v3
+		}
+		c[v3] = append(c[v3],props...)
+	}
+	return warnings,nil
+}
+
+/*
+remove and return the properties of name,ignore case of the key
+ */
+func (c Card) popProps(name string) []*Property {
+	var props []*Property
+	for k,ps := range c{
+		if strings.EqualFold(k,name){
+			props = append(props,ps...)
+			delete(c,k)
+		}
+	}
+	return props
+}
+
+/*
+find the ADR a vCard 3.0 LABEL belong to:same group,or same types
+ */
+func (c Card) labelAddress(l *Property) *Property {
+	adrs := c[PropAdr]
+	for _,adr := range adrs{
+		if l.Group != "" && adr.Group == l.Group{
+			return adr
+		}
+	}
+	for _,adr := range adrs{
+		if _,label := findParam(adr.Params,paramLabel);label != ""{
+			continue
+		}
+		same := len(adr.Params[ParamType]) == len(l.Params[ParamType])
+		for _,t := range l.Params[ParamType]{
+			if !adr.IsHasType(t){
+				same = false
+			}
+		}
+		if same{
+			return adr
+		}
+	}
+	return nil
+}
+
+/*
+remove the LABEL param of an ADR and return it as a vCard 3.0 LABEL property,
+nil if the ADR has no LABEL
+ */
+func labelProperty(adr *Property) *Property {
+	lk,label := findParam(adr.Params,paramLabel)
+	if lk == ""{
+		return nil
+	}
+	delete(adr.Params,lk)
+	lp := &Property{Group:adr.Group,Name:propLabel,Params:map[string][]string{}}
+	if types,ok := adr.Params[ParamType];ok{
+		lp.Params[ParamType] = append([]string(nil),types...)
+	}
+	lp.Value = [][]string{{label}}
+	return lp
+}
+
+func removeType(p *Property,t string)  {
+	var types []string
+	for _,tt := range p.Params[ParamType]{
+		if !strings.EqualFold(tt,t){
+			types = append(types,tt)
+		}
+	}
+	if types == nil{
+		delete(p.Params,ParamType)
+	}else{
+		p.Params[ParamType] = types
+	}
+}
+
+/*
+return the UID or else the FN of a vCard 3.0 embedded AGENT card
+ */
+func agentCard(text string) (uid,fn string) {
+	card,err := NewDecoder(strings.NewReader(text)).Decode()
+	if err != nil{
+		return "",text
+	}
+	if p := card.Get(PropUid);p != nil{
+		return p.GetValueFirstText(),""
+	}
+	if p := card.Get(PropFN);p != nil{
+		return "",p.GetValueFirstText()
+	}
+	return "",text
+}
+
+/*
+vCard 4.0 date has no '-',e.g. 1996-04-15 become 19960415
+ */
+func v4Date(s string) string {
+	if len(s) == 10 && s[4] == '-' && s[7] == '-'{
+		return s[:4]+s[5:7]+s[8:]
+	}
+	return s
+}
+
+/*
+convert inline base64 of PHOTO,LOGO,SOUND and KEY to a data: URI
+ */
+func v4Media(p *Property)  {
+	ek,enc := findParam(p.Params,paramEncoding)
+	vk,val := findParam(p.Params,ParamValue)
+	if ek == ""{
+		if strings.EqualFold(val,"uri") || strings.EqualFold(val,"url"){
+			//uri is the default value type of media in vCard 4.0
+			delete(p.Params,vk)
+		}else if vk == "" && strings.EqualFold(p.Name,PropKey){
+			//vCard 3.0 KEY default to text
+			p.SetParam(ParamValue,"text")
+		}
+		return
+	}
+	if !strings.EqualFold(enc,"b") && !strings.EqualFold(enc,"BASE64"){
+		return
+	}
+	delete(p.Params,ek)
+	delete(p.Params,vk)
+	mediatype := "application/octet-stream"
+	if types := p.Params[ParamType];len(types) > 0{
+		mediatype = mediaTypeOf(p.Name,types[0])
+		delete(p.Params,ParamType)
+	}
+	data := strings.Join(strings.Fields(joinValue(p.Value)),"")
+	p.Value = parseValues("data:"+mediatype+";base64,"+data)
+}
+
+/*
+media type of a vCard 3.0 TYPE param,e.g. JPEG of a PHOTO is image/jpeg
+ */
+func mediaTypeOf(prop,t string) string {
+	t = strings.ToLower(t)
+	if strings.Contains(t,"/"){
+		return t
+	}
+	switch strings.ToUpper(prop) {
+	case PropSound:
+		return "audio/"+t
+	case PropKey:
+		switch t {
+		case "pgp":
+			return "application/pgp-keys"
+		case "x509":
+			return "application/pkix-cert"
+		}
+		return "application/"+t
+	}
+	return "image/"+t
+}
+
+/*
+convert data: URI of PHOTO,LOGO,SOUND and KEY to inline base64
+ */
+func legacyMedia(p *Property,version string)  {
+	if ek,_ := findParam(p.Params,paramEncoding);ek != ""{
+		//already inline,e.g. a card decoded from vCard 3.0
+		delete(p.Params,ek)
+		if version == "2.1"{
+			p.Params[paramEncoding] = []string{"BASE64"}
+		}else{
+			p.Params[paramEncoding] = []string{"b"}
+		}
+		return
+	}
+	uri := joinValue(p.Value)
+	mk,mediatype := findParam(p.Params,ParamMediatype)
+	delete(p.Params,mk)
+	if !strings.HasPrefix(strings.ToLower(uri),"data:"){
+		if vk,val := findParam(p.Params,ParamValue);strings.EqualFold(val,"text"){
+			delete(p.Params,vk)
+		}else if version == "2.1"{
+			p.SetParam(ParamValue,"URL")
+		}else{
+			p.SetParam(ParamValue,"uri")
+		}
+		return
+	}
+	i := strings.IndexByte(uri,',')
+	if i < 0{
+		return
+	}
+	header,data := uri[len("data:"):i],uri[i+1:]
+	if !strings.HasSuffix(strings.ToLower(header),";base64"){
+		return
+	}
+	if mediatype == ""{
+		mediatype = header[:len(header)-len(";base64")]
+	}
+	if version == "2.1"{
+		p.SetParam(paramEncoding,"BASE64")
+	}else{
+		p.SetParam(paramEncoding,"b")
+	}
+	if j := strings.IndexByte(mediatype,'/');j >= 0{
+		p.SetParam(ParamType,strings.ToUpper(mediatype[j+1:]))
+	}
+	p.Value = [][]string{{data}}
+}
@@ -0,0 +1,233 @@
+package go_vcard
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCard_ToV4(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"3.0"}}}},
+		"FN":      []*Property{{Name: "FN", Value: [][]string{{"Joe Bloggs"}}}},
+		"EMAIL": []*Property{{Name: "EMAIL",
+			Value:  [][]string{{"me@joebloggs.com"}},
+			Params: map[string][]string{"TYPE": {"INTERNET", "HOME", "pref"}},
+		}},
+		"ADR": []*Property{{Name: "ADR",
+			Value:  [][]string{{""}, {""}, {"1 Trafalgar Square"}, {"London"}, {""}, {"WC2N"}, {"United Kingdom"}},
+			Params: map[string][]string{"TYPE": {"HOME"}},
+		}},
+		"LABEL": []*Property{{Name: "LABEL",
+			Value:  [][]string{{"1 Trafalgar Square\nLondon"}},
+			Params: map[string][]string{"TYPE": {"HOME"}},
+		}},
+		"PHOTO": []*Property{{Name: "PHOTO",
+			Value:  [][]string{{"MIICajCCAdOgAwIBAgICBEUwDQYJKoZIhv"}},
+			Params: map[string][]string{"ENCODING": {"b"}, "TYPE": {"JPEG"}},
+		}},
+		"AGENT": []*Property{{Name: "AGENT",
+			Value:  [][]string{{"http://example.com/agent.vcf"}},
+			Params: map[string][]string{"VALUE": {"uri"}},
+		}},
+		"X-ABUID":       []*Property{{Name: "X-ABUID", Value: [][]string{{"5AD380FD-B2DE-4261-BA99-DE1D1DB52FBE:ABPerson"}}}},
+		"X-ANNIVERSARY": []*Property{{Name: "X-ANNIVERSARY", Value: [][]string{{"2010-05-01"}}}},
+		"X-GENDER":      []*Property{{Name: "X-GENDER", Value: [][]string{{"Female"}}}},
+	}
+
+	if _, err := card.ToV4(); err != nil {
+		t.Fatal("Expected no error when converting to vCard 4.0, got:", err)
+	}
+
+	if v := card.Get(PropVersion).GetValueFirstText(); v != "4.0" {
+		t.Errorf("Expected VERSION to be 4.0 but got %q", v)
+	}
+	if email := card.Get(PropEmail); email.GetFirstParamVal(ParamPref) != "1" || email.IsHasType("pref") {
+		t.Errorf("Expected EMAIL to have PREF=1 but got %v", email.Params)
+	}
+	if label := card.Get(PropAdr).GetFirstParamVal("LABEL"); label != "1 Trafalgar Square\nLondon" {
+		t.Errorf("Expected ADR LABEL param but got %q", label)
+	}
+	if card.Get("LABEL") != nil {
+		t.Error("Expected LABEL property to be removed")
+	}
+	if photo := joinValue(card.Value(PropPhoto)); photo != "data:image/jpeg;base64,MIICajCCAdOgAwIBAgICBEUwDQYJKoZIhv" {
+		t.Errorf("Expected PHOTO data URI but got %q", photo)
+	}
+	if rel := card.Get(PropRelated); rel == nil || !rel.IsHasType("agent") || rel.GetValueFirstText() != "http://example.com/agent.vcf" {
+		t.Errorf("Expected RELATED;TYPE=agent but got %+v", rel)
+	}
+	if uid := card.Get(PropUid).GetValueFirstText(); uid != "urn:uuid:5ad380fd-b2de-4261-ba99-de1d1db52fbe" {
+		t.Errorf("Expected UID from X-ABUID but got %q", uid)
+	}
+	if ann := card.Get(PropAnniversary).GetValueFirstText(); ann != "20100501" {
+		t.Errorf("Expected ANNIVERSARY from X-ANNIVERSARY but got %q", ann)
+	}
+	if sex, _ := card.Gender(); sex != SexFemale {
+		t.Errorf("Expected GENDER from X-GENDER but got %q", sex)
+	}
+}
+
+func TestCard_ToV3(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"KIND":    []*Property{{Name: "KIND", Value: [][]string{{"group"}}}},
+		"GENDER":  []*Property{{Name: "GENDER", Value: [][]string{{"M"}}}},
+		"MEMBER":  []*Property{{Name: "MEMBER", Value: [][]string{{"urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af"}}}},
+		"FN": []*Property{{Name: "FN",
+			Value:  [][]string{{"The Doe family"}},
+			Params: map[string][]string{"PID": {"1.1"}},
+		}},
+		"EMAIL": []*Property{
+			{Name: "EMAIL", Value: [][]string{{"me@example.org"}}, Params: map[string][]string{"PREF": {"2"}}},
+			{Name: "EMAIL", Value: [][]string{{"me@example.com"}}, Params: map[string][]string{"PREF": {"1"}}},
+		},
+		"ADR": []*Property{{Name: "ADR",
+			Value:  [][]string{{""}, {""}, {"1 Trafalgar Square"}, {"London"}, {""}, {"WC2N"}, {"United Kingdom"}},
+			Params: map[string][]string{"TYPE": {"home"}, "LABEL": {"1 Trafalgar Square\nLondon"}},
+		}},
+		"CLIENTPIDMAP": []*Property{{Name: "CLIENTPIDMAP", Value: [][]string{{"1"}, {"urn:uuid:53e374d9-337e-4727-8803-a1e9c14e0556"}}}},
+	}
+
+	pref := card.Pref(PropEmail)
+	warnings, err := card.ToV3()
+	if err != nil {
+		t.Fatal("Expected no error when converting to vCard 3.0, got:", err)
+	}
+
+	for _, name := range []string{"KIND", "GENDER", "MEMBER", "CLIENTPIDMAP"} {
+		if card.Get(name) != nil {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+	if v := card.Get("X-ADDRESSBOOKSERVER-KIND").GetValueFirstText(); v != KindGroup {
+		t.Errorf("Expected X-ADDRESSBOOKSERVER-KIND to be %q but got %q", KindGroup, v)
+	}
+	if v := card.Get("X-GENDER").GetValueFirstText(); v != "Male" {
+		t.Errorf("Expected X-GENDER to be %q but got %q", "Male", v)
+	}
+	if card.Get("X-ADDRESSBOOKSERVER-MEMBER") == nil {
+		t.Error("Expected X-ADDRESSBOOKSERVER-MEMBER")
+	}
+	if params := card.Get(PropFN).Params; len(params) != 0 {
+		t.Errorf("Expected PID param to be removed but got %v", params)
+	}
+	for _, email := range card[PropEmail] {
+		if email.IsHasType("pref") != (email == pref) {
+			t.Errorf("Expected only the most preferred EMAIL to have TYPE=pref but got %+v", email)
+		}
+	}
+	if label := card.Get("LABEL"); label == nil || !reflect.DeepEqual(label.Params[ParamType], []string{"home"}) {
+		t.Errorf("Expected LABEL property but got %+v", label)
+	}
+
+	for _, w := range []string{"PID", "CLIENTPIDMAP", "PREF"} {
+		found := false
+		for _, warning := range warnings {
+			if strings.Contains(warning, w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected a warning about %s but got %q", w, warnings)
+		}
+	}
+}
+
+func TestCard_convertRoundTrip(t *testing.T) {
+	for _, version := range []string{"3.0", "4.0"} {
+		card := Card{
+			PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+			PropFN:      {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		}
+		if version == "4.0" {
+			card[PropVersion][0].Value = [][]string{{"3.0"}}
+			_, err := card.ToV4()
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := card.ToV3(); err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		if err := NewEncoder(&b).Encode(card); err != nil {
+			t.Fatalf("Expected no error when encoding a card converted to %s, got: %v", version, err)
+		}
+		if !strings.Contains(b.String(), "\r\nVERSION:"+version+"\r\n") {
+			t.Errorf("Expected VERSION:%s but got %q", version, b.String())
+		}
+		decoded, err := NewDecoder(&b).Decode()
+		if err != nil {
+			t.Fatalf("Expected no error when decoding a card converted to %s, got: %v", version, err)
+		}
+		if v := decoded.Get(PropVersion).GetValueFirstText(); v != version {
+			t.Errorf("Expected decoded VERSION %s but got %q", version, v)
+		}
+	}
+
+	// a VERSION without name, e.g. set by Card.SetValue
+	card := Card{PropFN: {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}}}
+	card.SetValue(PropVersion, [][]string{{"4.0"}})
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecoder(&b).Decode(); err != nil {
+		t.Error("Expected no error when decoding a card with a VERSION set by SetValue, got:", err)
+	}
+}
+
+func TestCard_ToV3ToV4(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropKind:    {{Name: PropKind, Value: [][]string{{"group"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"The Doe family"}}}},
+		PropN: {{Name: PropN, Value: [][]string{{"Doe"}, {""}, {""}, {""}, {""}},
+			Params: map[string][]string{ParamSortAs: {"Doe", "Family"}}}},
+		PropMember: {
+			{Name: PropMember, Value: [][]string{{"urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af"}}},
+			{Name: PropMember, Value: [][]string{{"urn:uuid:b8767877-b4a1-4c70-9acc-505d3819e519"}}},
+		},
+		PropRelated: {{Name: PropRelated, Value: [][]string{{"urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6"}},
+			Params: map[string][]string{ParamType: {"friend"}}}},
+		PropLang: {{Name: PropLang, Value: [][]string{{"fr"}}}},
+	}
+	if _, err := card.ToV3(); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal(err)
+	}
+	card, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := card.ToV4(); err != nil {
+		t.Fatal(err)
+	}
+
+	if kind := card.Kind(); kind != KindGroup {
+		t.Errorf("Expected KIND %q after round trip but got %q", KindGroup, kind)
+	}
+	if members := card.Members(); len(members) != 2 || members[0] != "urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af" {
+		t.Errorf("Expected 2 members after round trip but got %v", members)
+	}
+	if rel := card.Get(PropRelated); rel == nil || !rel.IsHasType("friend") {
+		t.Errorf("Expected RELATED after round trip but got %+v", rel)
+	}
+	if lang := card.Get(PropLang).GetValueFirstText(); lang != "fr" {
+		t.Errorf("Expected LANG after round trip but got %q", lang)
+	}
+	if sortAs := card.Get(PropN).Params[ParamSortAs]; !reflect.DeepEqual(sortAs, []string{"Doe", "Family"}) {
+		t.Errorf("Expected N SORT-AS after round trip but got %v", sortAs)
+	}
+	for _, name := range []string{"X-ADDRESSBOOKSERVER-KIND", "X-ADDRESSBOOKSERVER-MEMBER", "X-RELATED", "X-LANG", "SORT-STRING"} {
+		if card.Get(name) != nil {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+}
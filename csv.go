@@ -0,0 +1,554 @@
+package go_vcard
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+CSVColumn map a CSV column to a property,a component of its value or
+one of its params
+ */
+type CSVColumn struct {
+	Header string
+
+	//vCard property,e.g. EMAIL
+	Property string
+
+	//index of the component for structured values,e.g. 3 is the locality of ADR
+	Component int
+
+	//the column hold the properties having all these types,e.g. Outlook
+	//"Home Phone" is TEL;TYPE=home
+	Types []string
+
+	//1-based index among the properties of Property and Types,for the
+	//numbered columns like "E-mail 2 Address".0 is the same as 1
+	Index int
+
+	//the column hold this param instead of the value,e.g. LABEL.TYPE columns
+	//hold a label of CSVMapping.TypeLabels,prefixed by "* " for the preferred
+	Param string
+}
+
+/*
+CSVMapping is the columns of a CSV profile
+ */
+type CSVMapping struct {
+	Columns []CSVColumn
+
+	//vCard TYPE to the label written in TYPE columns,e.g. "cell":"Mobile",
+	//other types are written as they are
+	TypeLabels map[string]string
+
+	//separator of several values in one cell,e.g. " ::: " of Google for two
+	//phones of the same type.empty if a cell hold one value
+	MultiValueSeparator string
+}
+
+//number of components of the structured properties
+var csvComponents = map[string]int{PropN:5,PropAdr:7}
+
+/*
+Google Contacts CSV
+ */
+var GoogleCSVMapping = googleCSVMapping()
+
+/*
+Outlook CSV
+ */
+var OutlookCSVMapping = outlookCSVMapping()
+
+func googleCSVMapping() CSVMapping {
+	m := CSVMapping{
+		Columns:[]CSVColumn{
+			{Header:"Name",Property:PropFN},
+			{Header:"Given Name",Property:PropN,Component:1},
+			{Header:"Additional Name",Property:PropN,Component:2},
+			{Header:"Family Name",Property:PropN,Component:0},
+			{Header:"Name Prefix",Property:PropN,Component:3},
+			{Header:"Name Suffix",Property:PropN,Component:4},
+			{Header:"Nickname",Property:PropNickName},
+			{Header:"Birthday",Property:PropBday},
+			{Header:"Notes",Property:PropNote},
+		},
+		TypeLabels:map[string]string{
+			"home":"Home","work":"Work","cell":"Mobile","fax":"Fax",
+			"pager":"Pager","main":"Main","other":"Other",
+		},
+		MultiValueSeparator:" ::: ",
+	}
+	for i := 1;i <= 3;i++{
+		prefix := fmt.Sprintf("E-mail %d - ",i)
+		m.Columns = append(m.Columns,
+			CSVColumn{Header:prefix+"Type",Property:PropEmail,Index:i,Param:ParamType},
+			CSVColumn{Header:prefix+"Value",Property:PropEmail,Index:i})
+	}
+	for i := 1;i <= 3;i++{
+		prefix := fmt.Sprintf("Phone %d - ",i)
+		m.Columns = append(m.Columns,
+			CSVColumn{Header:prefix+"Type",Property:PropTel,Index:i,Param:ParamType},
+			CSVColumn{Header:prefix+"Value",Property:PropTel,Index:i})
+	}
+	for i := 1;i <= 2;i++{
+		prefix := fmt.Sprintf("Address %d - ",i)
+		m.Columns = append(m.Columns,
+			CSVColumn{Header:prefix+"Type",Property:PropAdr,Index:i,Param:ParamType},
+			CSVColumn{Header:prefix+"Formatted",Property:PropAdr,Index:i,Param:paramLabel},
+			CSVColumn{Header:prefix+"Street",Property:PropAdr,Index:i,Component:2},
+			CSVColumn{Header:prefix+"City",Property:PropAdr,Index:i,Component:3},
+			CSVColumn{Header:prefix+"PO Box",Property:PropAdr,Index:i,Component:0},
+			CSVColumn{Header:prefix+"Region",Property:PropAdr,Index:i,Component:4},
+			CSVColumn{Header:prefix+"Postal Code",Property:PropAdr,Index:i,Component:5},
+			CSVColumn{Header:prefix+"Country",Property:PropAdr,Index:i,Component:6},
+			CSVColumn{Header:prefix+"Extended Address",Property:PropAdr,Index:i,Component:1})
+	}
+	m.Columns = append(m.Columns,
+		CSVColumn{Header:"Organization 1 - Name",Property:PropOrg},
+		CSVColumn{Header:"Organization 1 - Department",Property:PropOrg,Component:1},
+		CSVColumn{Header:"Organization 1 - Title",Property:PropTiTle})
+	for i := 1;i <= 2;i++{
+		prefix := fmt.Sprintf("Website %d - ",i)
+		m.Columns = append(m.Columns,
+			CSVColumn{Header:prefix+"Type",Property:PropUrl,Index:i,Param:ParamType},
+			CSVColumn{Header:prefix+"Value",Property:PropUrl,Index:i})
+	}
+	return m
+}
+
+func outlookCSVMapping() CSVMapping {
+	m := CSVMapping{
+		Columns:[]CSVColumn{
+			{Header:"Title",Property:PropN,Component:3},
+			{Header:"First Name",Property:PropN,Component:1},
+			{Header:"Middle Name",Property:PropN,Component:2},
+			{Header:"Last Name",Property:PropN,Component:0},
+			{Header:"Suffix",Property:PropN,Component:4},
+			{Header:"Company",Property:PropOrg},
+			{Header:"Department",Property:PropOrg,Component:1},
+			{Header:"Job Title",Property:PropTiTle},
+		},
+	}
+	//addresses without type are "Other"
+	for _,a := range []struct{prefix string;types []string}{
+		{"Business",[]string{"work"}},{"Home",[]string{"home"}},{"Other",nil},
+	}{
+		m.Columns = append(m.Columns,
+			CSVColumn{Header:a.prefix+" Street",Property:PropAdr,Types:a.types,Component:2},
+			CSVColumn{Header:a.prefix+" City",Property:PropAdr,Types:a.types,Component:3},
+			CSVColumn{Header:a.prefix+" State",Property:PropAdr,Types:a.types,Component:4},
+			CSVColumn{Header:a.prefix+" Postal Code",Property:PropAdr,Types:a.types,Component:5},
+			CSVColumn{Header:a.prefix+" Country/Region",Property:PropAdr,Types:a.types,Component:6})
+	}
+	m.Columns = append(m.Columns,
+		CSVColumn{Header:"Business Fax",Property:PropTel,Types:[]string{"work","fax"}},
+		CSVColumn{Header:"Business Phone",Property:PropTel,Types:[]string{"work"}},
+		CSVColumn{Header:"Business Phone 2",Property:PropTel,Types:[]string{"work"},Index:2},
+		CSVColumn{Header:"Home Fax",Property:PropTel,Types:[]string{"home","fax"}},
+		CSVColumn{Header:"Home Phone",Property:PropTel,Types:[]string{"home"}},
+		CSVColumn{Header:"Home Phone 2",Property:PropTel,Types:[]string{"home"},Index:2},
+		CSVColumn{Header:"Mobile Phone",Property:PropTel,Types:[]string{"cell"}},
+		CSVColumn{Header:"Pager",Property:PropTel,Types:[]string{"pager"}},
+		CSVColumn{Header:"Other Phone",Property:PropTel},
+		CSVColumn{Header:"E-mail Address",Property:PropEmail},
+		CSVColumn{Header:"E-mail 2 Address",Property:PropEmail,Index:2},
+		CSVColumn{Header:"E-mail 3 Address",Property:PropEmail,Index:3},
+		CSVColumn{Header:"Notes",Property:PropNote},
+		CSVColumn{Header:"Web Page",Property:PropUrl})
+	return m
+}
+
+/*
+property slot of a column:the Index-th property of Property having Types
+ */
+type csvSlot struct {
+	property string
+	types string
+	index int
+}
+
+func (col *CSVColumn) slot() csvSlot {
+	index := col.Index
+	if index == 0{
+		index = 1
+	}
+	types := make([]string,len(col.Types))
+	for i,t := range col.Types{
+		types[i] = strings.ToLower(t)
+	}
+	sort.Strings(types)
+	return csvSlot{strings.ToUpper(col.Property),strings.Join(types,","),index}
+}
+
+/*
+CSVReader read cards from CSV,one card per row.the first row is the header,
+columns not in the mapping are ignored
+ */
+type CSVReader struct {
+	r *csv.Reader
+	mapping CSVMapping
+	columns []*CSVColumn //column of each CSV field,nil for unknown headers
+}
+
+func NewCSVReader(r io.Reader,m CSVMapping) *CSVReader {
+	cr := &CSVReader{r:csv.NewReader(r),mapping:m}
+	cr.r.FieldsPerRecord = -1
+	return cr
+}
+
+/*
+read the next card,return io.EOF when there is no more row
+ */
+func (cr *CSVReader) Read() (Card,error) {
+	if cr.columns == nil{
+		if err := cr.readHeader();err != nil{
+			return nil,err
+		}
+	}
+	for{
+		row,err := cr.r.Read()
+		if err != nil{
+			return nil,err
+		}
+		c := cr.card(row)
+		if len(c) > 1{
+			return c,nil
+		}
+		//skip rows without any value
+	}
+}
+
+/*
+read all the cards
+ */
+func (cr *CSVReader) ReadAll() ([]Card,error) {
+	var cards []Card
+	for{
+		c,err := cr.Read()
+		if err == io.EOF{
+			return cards,nil
+		}
+		if err != nil{
+			return nil,err
+		}
+		cards = append(cards,c)
+	}
+}
+
+func (cr *CSVReader) readHeader() error {
+	header,err := cr.r.Read()
+	if err != nil{
+		return err
+	}
+	cr.columns = make([]*CSVColumn,len(header))
+	for i,h := range header{
+		h = strings.TrimSpace(strings.TrimPrefix(h,"\ufeff"))
+		for ci := range cr.mapping.Columns{
+			if col := &cr.mapping.Columns[ci];strings.EqualFold(col.Header,h){
+				cr.columns[i] = col
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (cr *CSVReader) card(row []string) Card {
+	cells := make(map[*CSVColumn]string)
+	for i,v := range row{
+		if i < len(cr.columns) && cr.columns[i] != nil && strings.TrimSpace(v) != ""{
+			cells[cr.columns[i]] = strings.TrimSpace(v)
+		}
+	}
+	c := make(Card)
+	c.Set(PropVersion,&Property{Name:PropVersion,Value:[][]string{{"4.0"}}})
+	props := make(map[csvSlot]*Property)
+	labels := invertLabels(cr.mapping.TypeLabels)
+	//properties are added in the order of the mapping,so numbered columns
+	//keep their order
+	for ci := range cr.mapping.Columns{
+		col := &cr.mapping.Columns[ci]
+		v,ok := cells[col]
+		if !ok{
+			continue
+		}
+		s := col.slot()
+		p := props[s]
+		if p == nil{
+			p = &Property{Name:s.property}
+			for _,t := range col.Types{
+				p.AddParam(ParamType,strings.ToLower(t))
+			}
+			props[s] = p
+			c.Add(s.property,p)
+		}
+		switch {
+		case strings.EqualFold(col.Param,ParamType):
+			if strings.HasPrefix(v,"* "){
+				v = strings.TrimPrefix(v,"* ")
+				p.SetParam(ParamPref,"1")
+			}
+			if t,ok := labels[strings.ToLower(v)];ok{
+				v = t
+			}
+			if !p.IsHasType(v){
+				p.AddParam(ParamType,strings.ToLower(v))
+			}
+		case col.Param != "":
+			p.SetParam(strings.ToUpper(col.Param),v)
+		default:
+			for len(p.Value) <= col.Component{
+				p.Value = append(p.Value,[]string{""})
+			}
+			p.Value[col.Component] = []string{v}
+		}
+	}
+	for _,ps := range c{
+		for _,p := range ps{
+			for len(p.Value) < csvComponents[p.Name]{
+				p.Value = append(p.Value,[]string{""})
+			}
+			if p.Value == nil{
+				//e.g. only a type column
+				p.Value = [][]string{{""}}
+			}
+		}
+	}
+	if sep := cr.mapping.MultiValueSeparator;sep != ""{
+		for k,ps := range c{
+			var split []*Property
+			for _,p := range ps{
+				split = append(split,splitCSVValue(p,sep)...)
+			}
+			c[k] = split
+		}
+	}
+	if c.Get(PropFN) == nil{
+		if fn := csvFormattedName(c);fn != ""{
+			c.Add(PropFN,&Property{Name:PropFN,Value:[][]string{{fn}}})
+		}
+	}
+	return c
+}
+
+/*
+split a property whose cells hold several values into one property per value,
+the i-th property take the i-th value of each component and the params of p
+ */
+func splitCSVValue(p *Property,sep string) []*Property {
+	n := 1
+	for _,vals := range p.Value{
+		for _,v := range vals{
+			if k := strings.Count(v,sep)+1;k > n{
+				n = k
+			}
+		}
+	}
+	if n == 1{
+		return []*Property{p}
+	}
+	props := make([]*Property,n)
+	for i := range props{
+		cp := p.copy()
+		for _,vals := range cp.Value{
+			for j,v := range vals{
+				parts := strings.Split(v,sep)
+				vals[j] = ""
+				if i < len(parts){
+					vals[j] = strings.TrimSpace(parts[i])
+				}
+			}
+		}
+		props[i] = &cp
+	}
+	return props
+}
+
+/*
+FN is required by vCard 4.0,it is made of N or ORG when there is no column of it
+ */
+func csvFormattedName(c Card) string {
+	if n := c.Name();n != nil{
+		var parts []string
+		for _,s := range []string{n.HonorificPrefix,n.GivenName,n.AdditionalName,n.FamilyName,n.HonorificSuffix}{
+			if s != ""{
+				parts = append(parts,s)
+			}
+		}
+		if len(parts) > 0{
+			return strings.Join(parts," ")
+		}
+	}
+	if org := c.Get(PropOrg);org != nil{
+		return component(org.Value,0)
+	}
+	return ""
+}
+
+func invertLabels(labels map[string]string) map[string]string {
+	inv := make(map[string]string,len(labels))
+	for t,l := range labels{
+		inv[strings.ToLower(l)] = t
+	}
+	return inv
+}
+
+/*
+CSVWriter write cards as CSV,one row per card after the header.a property
+that fits no column is not written
+ */
+type CSVWriter struct {
+	w *csv.Writer
+	mapping CSVMapping
+	wroteHeader bool
+}
+
+func NewCSVWriter(w io.Writer,m CSVMapping) *CSVWriter {
+	return &CSVWriter{w:csv.NewWriter(w),mapping:m}
+}
+
+/*
+write a card,the header is written before the first card.Flush must be
+called to write the buffered rows
+ */
+func (cw *CSVWriter) Write(c Card) error {
+	if !cw.wroteHeader{
+		header := make([]string,len(cw.mapping.Columns))
+		for i,col := range cw.mapping.Columns{
+			header[i] = col.Header
+		}
+		if err := cw.w.Write(header);err != nil{
+			return err
+		}
+		cw.wroteHeader = true
+	}
+	return cw.w.Write(cw.row(c))
+}
+
+/*
+write the cards and flush
+ */
+func (cw *CSVWriter) WriteAll(cards []Card) error {
+	for _,c := range cards{
+		if err := cw.Write(c);err != nil{
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *CSVWriter) row(c Card) []string {
+	props := cw.assign(c)
+	row := make([]string,len(cw.mapping.Columns))
+	for i := range cw.mapping.Columns{
+		col := &cw.mapping.Columns[i]
+		p := props[col.slot()]
+		if p == nil{
+			continue
+		}
+		switch {
+		case strings.EqualFold(col.Param,ParamType):
+			//only the preferred property of the card is marked
+			row[i] = cw.typeLabel(p,col.Types,p == c.Pref(col.slot().property))
+		case col.Param != "":
+			row[i] = p.GetFirstParamVal(strings.ToUpper(col.Param))
+		default:
+			row[i] = component(p.Value,col.Component)
+		}
+	}
+	return row
+}
+
+/*
+assign the properties to the slots of the columns,the slots with more types
+are assigned first so that e.g. a home fax is not taken by "Home Phone"
+ */
+func (cw *CSVWriter) assign(c Card) map[csvSlot]*Property {
+	var slots []csvSlot
+	ntypes := make(map[csvSlot]int)
+	for i := range cw.mapping.Columns{
+		col := &cw.mapping.Columns[i]
+		s := col.slot()
+		if _,ok := ntypes[s];ok{
+			continue
+		}
+		ntypes[s] = len(col.Types)
+		slots = append(slots,s)
+	}
+	sort.SliceStable(slots,func(i,j int) bool {
+		if ntypes[slots[i]] != ntypes[slots[j]]{
+			return ntypes[slots[i]] > ntypes[slots[j]]
+		}
+		return slots[i].index < slots[j].index
+	})
+	assigned := make(map[*Property]bool)
+	props := make(map[csvSlot]*Property)
+	for _,s := range slots{
+		for _,p := range c[s.property]{
+			if assigned[p] || !hasTypes(p,s.types){
+				continue
+			}
+			assigned[p] = true
+			props[s] = p
+			break
+		}
+	}
+	return props
+}
+
+func hasTypes(p *Property,types string) bool {
+	if types == ""{
+		return true
+	}
+	for _,t := range strings.Split(types,","){
+		if !p.IsHasType(t){
+			return false
+		}
+	}
+	return true
+}
+
+/*
+label of the first type of the property that is not a type of the column,
+marked with "* " when pref is true and the property has a preference
+ */
+func (cw *CSVWriter) typeLabel(p *Property,colTypes []string,pref bool) string {
+	label := ""
+	for _,t := range p.GetParamTypeList(){
+		t = strings.ToLower(t)
+		if t == "pref" || containsFold(colTypes,t){
+			continue
+		}
+		if l,ok := cw.mapping.TypeLabels[t];ok{
+			label = l
+		}else{
+			label = t
+		}
+		break
+	}
+	if !pref{
+		return label
+	}
+	if n,err := strconv.Atoi(p.GetFirstParamVal(ParamPref));(err == nil && n > 0) || p.IsHasType("pref"){
+		label = "* "+label
+	}
+	return label
+}
+
+func containsFold(list []string,s string) bool {
+	for _,e := range list{
+		if strings.EqualFold(e,s){
+			return true
+		}
+	}
+	return false
+}
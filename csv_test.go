@@ -0,0 +1,195 @@
+package go_vcard
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestCSVReader_outlook(t *testing.T) {
+	input := "First Name,Last Name,Company,Business Phone,Home Fax,Mobile Phone,E-mail Address,E-mail 2 Address,Business Street,Business City,Unknown\r\n" +
+		"Joe,Bloggs,ABC Inc.,+1 555 0100,+1 555 0101,+1 555 0102,joe@example.com,joe@home.example.com,1 Main St,Springfield,x\r\n" +
+		",,,,,,,,,,\r\n"
+
+	cards, err := NewCSVReader(strings.NewReader(input), OutlookCSVMapping).ReadAll()
+	if err != nil {
+		t.Fatal("Expected no error when reading Outlook CSV, got:", err)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("Expected 1 card but got %d", len(cards))
+	}
+	card := cards[0]
+
+	if n := card.Name(); n.GivenName != "Joe" || n.FamilyName != "Bloggs" {
+		t.Errorf("Expected name Joe Bloggs but got %+v", n)
+	}
+	if v := card.Get(PropFN).GetValueFirstText(); v != "Joe Bloggs" {
+		t.Errorf("Expected FN made of N but got %q", v)
+	}
+	tels := card[PropTel]
+	if len(tels) != 3 || !tels[0].IsHasType("work") || !tels[1].IsHasType("fax") || !tels[2].IsHasType("cell") {
+		t.Errorf("Expected work,fax and cell TEL but got %v", tels)
+	}
+	emails := card[PropEmail]
+	if len(emails) != 2 || emails[1].GetValueFirstText() != "joe@home.example.com" {
+		t.Errorf("Expected 2 EMAIL in column order but got %v", emails)
+	}
+	if a := card.Address(); a.StreetAddress != "1 Main St" || a.Locality != "Springfield" || !a.IsHasType("work") {
+		t.Errorf("Expected work address but got %+v", a)
+	}
+}
+
+func TestCSVWriter_numberedColumns(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		PropEmail: {
+			{Name: PropEmail, Value: [][]string{{"joe@example.com"}}},
+			{Name: PropEmail, Value: [][]string{{"joe@work.example.com"}}},
+		},
+		PropTel: {
+			{Name: PropTel, Params: map[string][]string{ParamType: {"home", "fax"}}, Value: [][]string{{"+1 555 0101"}}},
+			{Name: PropTel, Params: map[string][]string{ParamType: {"home"}}, Value: [][]string{{"+1 555 0100"}}},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := NewCSVWriter(&b, OutlookCSVMapping).WriteAll([]Card{card}); err != nil {
+		t.Fatal("Expected no error when writing Outlook CSV, got:", err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("Expected header and 1 row but got %q (%v)", rows, err)
+	}
+	got := make(map[string]string)
+	for i, h := range rows[0] {
+		got[h] = rows[1][i]
+	}
+	expected := map[string]string{
+		"E-mail Address":   "joe@example.com",
+		"E-mail 2 Address": "joe@work.example.com",
+		"E-mail 3 Address": "",
+		"Home Phone":       "+1 555 0100",
+		"Home Fax":         "+1 555 0101",
+	}
+	for h, v := range expected {
+		if got[h] != v {
+			t.Errorf("Expected column %q to be %q but got %q", h, v, got[h])
+		}
+	}
+}
+
+func TestCSV_googleRoundTrip(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		PropN:       {{Name: PropN, Value: [][]string{{"Bloggs"}, {"Joe"}, {""}, {""}, {""}}}},
+		PropTel: {
+			{Name: PropTel, Params: map[string][]string{ParamType: {"cell"}, ParamPref: {"1"}}, Value: [][]string{{"+1 555 0102"}}},
+			{Name: PropTel, Params: map[string][]string{ParamType: {"work"}}, Value: [][]string{{"+1 555 0100"}}},
+		},
+		PropAdr: {{
+			Name:   PropAdr,
+			Params: map[string][]string{ParamType: {"home"}, paramLabel: {"1 Main St\nSpringfield"}},
+			Value:  [][]string{{""}, {""}, {"1 Main St"}, {"Springfield"}, {""}, {"12345"}, {"USA"}},
+		}},
+	}
+
+	var b bytes.Buffer
+	if err := NewCSVWriter(&b, GoogleCSVMapping).WriteAll([]Card{card}); err != nil {
+		t.Fatal("Expected no error when writing Google CSV, got:", err)
+	}
+	if !strings.Contains(b.String(), "* Mobile,+1 555 0102,Work,+1 555 0100") {
+		t.Errorf("Expected phone type labels in Google CSV but got:\n%s", b.String())
+	}
+
+	got, err := NewCSVReader(&b, GoogleCSVMapping).Read()
+	if err != nil {
+		t.Fatal("Expected no error when reading Google CSV, got:", err)
+	}
+	if MatrixToString(got.Value(PropN)) != MatrixToString(card.Value(PropN)) {
+		t.Errorf("Expected N %q but got %q", card.Value(PropN), got.Value(PropN))
+	}
+	tels := got[PropTel]
+	if len(tels) != 2 || !tels[0].IsHasType("cell") || tels[0].GetFirstParamVal(ParamPref) != "1" || !tels[1].IsHasType("work") {
+		t.Errorf("Expected preferred cell and work TEL but got %v", tels)
+	}
+	adr := got.Get(PropAdr)
+	if adr.GetFirstParamVal(paramLabel) != "1 Main St\nSpringfield" || MatrixToString(adr.Value) != MatrixToString(card[PropAdr][0].Value) {
+		t.Errorf("Expected ADR to round-trip but got %+v", adr)
+	}
+}
+
+func TestCSVReader_encodeDecode(t *testing.T) {
+	input := "First Name,Last Name,E-mail Address\r\n" +
+		"Joe,Bloggs,joe@example.com\r\n"
+
+	cards, err := NewCSVReader(strings.NewReader(input), OutlookCSVMapping).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cards[0].Get(PropVersion); p == nil || p.Name != PropVersion {
+		t.Errorf("Expected a VERSION property named %s but got %+v", PropVersion, p)
+	}
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(cards[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "\r\nVERSION:4.0\r\n") {
+		t.Errorf("Expected VERSION:4.0 in %q", b.String())
+	}
+	card, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding the card read from CSV, got:", err)
+	}
+	if v := card.Get(PropVersion).GetValueFirstText(); v != "4.0" {
+		t.Errorf("Expected VERSION 4.0 but got %q", v)
+	}
+	if v := card.Get(PropEmail).GetValueFirstText(); v != "joe@example.com" {
+		t.Errorf("Expected EMAIL joe@example.com but got %q", v)
+	}
+}
+
+func TestCSVReader_googleMultiValue(t *testing.T) {
+	input := "Name,Phone 1 - Type,Phone 1 - Value,Address 1 - Type,Address 1 - Street,Address 1 - City\r\n" +
+		"Joe Bloggs,Mobile,+1 555 0102 ::: +1 555 0103,Home,1 Main St ::: 2 High St,Springfield ::: Shelbyville\r\n"
+
+	card, err := NewCSVReader(strings.NewReader(input), GoogleCSVMapping).Read()
+	if err != nil {
+		t.Fatal("Expected no error when reading Google CSV, got:", err)
+	}
+	tels := card[PropTel]
+	if len(tels) != 2 || tels[0].GetValueFirstText() != "+1 555 0102" || tels[1].GetValueFirstText() != "+1 555 0103" {
+		t.Fatalf("Expected 2 TEL but got %v", tels)
+	}
+	if !tels[0].IsHasType("cell") || !tels[1].IsHasType("cell") {
+		t.Errorf("Expected both TEL to be cell but got %v", tels)
+	}
+	addrs := card.Addresses()
+	if len(addrs) != 2 || addrs[0].StreetAddress != "1 Main St" || addrs[1].StreetAddress != "2 High St" || addrs[1].Locality != "Shelbyville" {
+		t.Errorf("Expected 2 ADR but got %+v", addrs)
+	}
+	if fn := card.Get(PropFN).GetValueFirstText(); fn != "Joe Bloggs" {
+		t.Errorf("Expected FN Joe Bloggs but got %q", fn)
+	}
+}
+
+func TestCSV_onlyPreferredMarked(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		PropTel: {
+			{Name: PropTel, Params: map[string][]string{ParamType: {"cell"}, ParamPref: {"1"}}, Value: [][]string{{"+1 555 0102"}}},
+			{Name: PropTel, Params: map[string][]string{ParamType: {"work"}, ParamPref: {"1"}}, Value: [][]string{{"+1 555 0100"}}},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := NewCSVWriter(&b, GoogleCSVMapping).WriteAll([]Card{card}); err != nil {
+		t.Fatal("Expected no error when writing Google CSV, got:", err)
+	}
+	if !strings.Contains(b.String(), "* Mobile,+1 555 0102,Work,+1 555 0100") {
+		t.Errorf("Expected only the preferred phone to be marked in Google CSV but got:\n%s", b.String())
+	}
+}
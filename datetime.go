@@ -0,0 +1,323 @@
+package go_vcard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+a date-and-or-time value of RFC 6350,e.g. BDAY and ANNIVERSARY.the
+components may be reduced or truncated,e.g. --0415 has no year and T1022 has
+no date,the Has flags tell which components are present.a VALUE=text value
+is kept in Text only
+ */
+type DateAndOrTime struct {
+	Year,Month,Day int
+	Hour,Minute,Second int
+	Offset int //UTC offset in seconds,valid when HasZone
+
+	HasYear,HasMonth,HasDay bool
+	HasHour,HasMinute,HasSecond bool
+	HasZone bool
+
+	Text string
+}
+
+/*
+return a complete date-time of t,in the zone of t
+ */
+func NewDateAndOrTime(t time.Time) DateAndOrTime {
+	_,offset := t.Zone()
+	return DateAndOrTime{
+		Year:t.Year(),Month:int(t.Month()),Day:t.Day(),
+		Hour:t.Hour(),Minute:t.Minute(),Second:t.Second(),
+		Offset:offset,
+		HasYear:true,HasMonth:true,HasDay:true,
+		HasHour:true,HasMinute:true,HasSecond:true,
+		HasZone:true,
+	}
+}
+
+/*
+return a date without time,month and day start at 1
+ */
+func NewDate(year int,month time.Month,day int) DateAndOrTime {
+	return DateAndOrTime{Year:year,Month:int(month),Day:day,HasYear:true,HasMonth:true,HasDay:true}
+}
+
+/*
+parse a date,time,date-time or timestamp in the basic or extended ISO 8601
+form,e.g. 19960415,1996-04-15,--0415,---15,T102200Z,19961022T140000-0500
+ */
+func ParseDateAndOrTime(s string) (DateAndOrTime,error) {
+	var d DateAndOrTime
+	if s == ""{
+		return d,fmt.Errorf("vcard:empty date-and-or-time")
+	}
+	date,tm := s,""
+	if i := strings.IndexAny(s,"Tt");i >= 0{
+		date,tm = s[:i],s[i+1:]
+		if tm == ""{
+			return d,fmt.Errorf("vcard:invalid date-and-or-time %q",s)
+		}
+	}
+	if date != "" && !d.parseDate(date){
+		return d,fmt.Errorf("vcard:invalid date %q",s)
+	}
+	if tm != "" && !d.parseTime(tm){
+		return d,fmt.Errorf("vcard:invalid time %q",s)
+	}
+	if !d.valid(){
+		return d,fmt.Errorf("vcard:date-and-or-time out of range %q",s)
+	}
+	return d,nil
+}
+
+func (d *DateAndOrTime) parseDate(s string) bool {
+	n := 0
+	for n < len(s) && s[n] == '-'{
+		n++
+	}
+	//YYYY-MM is the only form keeping a '-' in the basic form
+	digits := s[:n]+strings.Replace(s[n:],"-","",-1)
+	if !isDigits(digits[n:]){
+		return false
+	}
+	v := digits[n:]
+	switch {
+	case n == 0 && len(v) == 4:
+		d.Year,d.HasYear = atoi(v),true
+	case n == 0 && len(v) == 6 && len(s) == 7:
+		d.Year,d.Month = atoi(v[:4]),atoi(v[4:])
+		d.HasYear,d.HasMonth = true,true
+	case n == 0 && len(v) == 8:
+		d.Year,d.Month,d.Day = atoi(v[:4]),atoi(v[4:6]),atoi(v[6:])
+		d.HasYear,d.HasMonth,d.HasDay = true,true,true
+	case n == 2 && len(v) == 2:
+		d.Month,d.HasMonth = atoi(v),true
+	case n == 2 && len(v) == 4:
+		d.Month,d.Day = atoi(v[:2]),atoi(v[2:])
+		d.HasMonth,d.HasDay = true,true
+	case n == 3 && len(v) == 2:
+		d.Day,d.HasDay = atoi(v),true
+	default:
+		return false
+	}
+	return true
+}
+
+func (d *DateAndOrTime) parseTime(s string) bool {
+	s = strings.Replace(s,":","",-1)
+	//leading '-' stand for omitted hour or minute
+	n := 0
+	for n < len(s) && s[n] == '-'{
+		n++
+	}
+	zone := ""
+	if z := strings.IndexAny(s[n:],"Zz+-");z >= 0{
+		s,zone = s[:n+z],s[n+z:]
+	}
+	v := s[n:]
+	if v == "" || len(v)%2 != 0 || n+len(v)/2 > 3 || !isDigits(v){
+		return false
+	}
+	vals := []*int{&d.Hour,&d.Minute,&d.Second}
+	has := []*bool{&d.HasHour,&d.HasMinute,&d.HasSecond}
+	for i := 0;i < len(v);i += 2{
+		*vals[n+i/2] = atoi(v[i:i+2])
+		*has[n+i/2] = true
+	}
+	if zone == ""{
+		return true
+	}
+	d.HasZone = true
+	if zone == "Z" || zone == "z"{
+		return true
+	}
+	h := zone[1:]
+	if (len(h) != 2 && len(h) != 4) || !isDigits(h){
+		return false
+	}
+	d.Offset = atoi(h[:2])*3600
+	if len(h) == 4{
+		d.Offset += atoi(h[2:])*60
+	}
+	if zone[0] == '-'{
+		d.Offset = -d.Offset
+	}
+	return true
+}
+
+func (d DateAndOrTime) valid() bool {
+	return (!d.HasMonth || d.Month >= 1 && d.Month <= 12) &&
+		(!d.HasDay || d.Day >= 1 && d.Day <= 31) &&
+		d.Hour <= 23 && d.Minute <= 59 && d.Second <= 60 &&
+		d.Offset > -24*3600 && d.Offset < 24*3600
+}
+
+func atoi(s string) int {
+	i,_ := strconv.Atoi(s)
+	return i
+}
+
+func (d DateAndOrTime) IsText() bool {
+	return d.Text != ""
+}
+
+func (d DateAndOrTime) HasDate() bool {
+	return d.HasYear || d.HasMonth || d.HasDay
+}
+
+func (d DateAndOrTime) HasTime() bool {
+	return d.HasHour || d.HasMinute || d.HasSecond
+}
+
+/*
+the year,month and day are all present
+ */
+func (d DateAndOrTime) IsComplete() bool {
+	return d.Text == "" && d.HasYear && d.HasMonth && d.HasDay
+}
+
+/*
+convert to a time.Time,only for a complete date.the omitted time is 00:00:00
+and a time without zone is in loc,nil loc is UTC
+ */
+func (d DateAndOrTime) Time(loc *time.Location) (time.Time,error) {
+	if !d.IsComplete(){
+		return time.Time{},fmt.Errorf("vcard:date-and-or-time %q is not a complete date",d.String())
+	}
+	if d.HasZone{
+		if d.Offset == 0{
+			loc = time.UTC
+		}else{
+			loc = time.FixedZone("",d.Offset)
+		}
+	}else if loc == nil{
+		loc = time.UTC
+	}
+	return time.Date(d.Year,time.Month(d.Month),d.Day,d.Hour,d.Minute,d.Second,0,loc),nil
+}
+
+/*
+the basic ISO 8601 form of vCard 4.0,e.g. 19960415,--0415,T102200-0500
+ */
+func (d DateAndOrTime) String() string {
+	return d.format(false)
+}
+
+/*
+the extended ISO 8601 form of vCard 3.0 and jCard,e.g. 1996-04-15,--04-15,
+T10:22:00-05:00
+ */
+func (d DateAndOrTime) Extended() string {
+	return d.format(true)
+}
+
+func (d DateAndOrTime) format(extended bool) string {
+	if d.Text != ""{
+		return d.Text
+	}
+	sep,tsep := "",""
+	if extended{
+		sep,tsep = "-",":"
+	}
+	var b strings.Builder
+	switch {
+	case d.HasYear && d.HasMonth && d.HasDay:
+		fmt.Fprintf(&b,"%04d%s%02d%s%02d",d.Year,sep,d.Month,sep,d.Day)
+	case d.HasYear && d.HasMonth:
+		//YYYY-MM has no basic form
+		fmt.Fprintf(&b,"%04d-%02d",d.Year,d.Month)
+	case d.HasYear:
+		fmt.Fprintf(&b,"%04d",d.Year)
+	case d.HasMonth && d.HasDay:
+		fmt.Fprintf(&b,"--%02d%s%02d",d.Month,sep,d.Day)
+	case d.HasMonth:
+		fmt.Fprintf(&b,"--%02d",d.Month)
+	case d.HasDay:
+		fmt.Fprintf(&b,"---%02d",d.Day)
+	}
+	if !d.HasTime(){
+		return b.String()
+	}
+	b.WriteString("T")
+	vals := []int{d.Hour,d.Minute,d.Second}
+	has := []bool{d.HasHour,d.HasMinute,d.HasSecond}
+	first := true
+	for i := range vals{
+		if !has[i]{
+			if first{
+				b.WriteString("-")
+			}
+			continue
+		}
+		if !first{
+			b.WriteString(tsep)
+		}
+		fmt.Fprintf(&b,"%02d",vals[i])
+		first = false
+	}
+	if d.HasZone{
+		if d.Offset == 0{
+			b.WriteString("Z")
+		}else{
+			sign,off := "+",d.Offset
+			if off < 0{
+				sign,off = "-",-off
+			}
+			fmt.Fprintf(&b,"%s%02d%s%02d",sign,off/3600,tsep,off%3600/60)
+		}
+	}
+	return b.String()
+}
+
+/*
+read a date-and-or-time property,a VALUE=text property is returned as Text
+ */
+func propDateAndOrTime(p *Property) (*DateAndOrTime,error) {
+	if p == nil{
+		return nil,nil
+	}
+	v := p.GetValueFirstText()
+	if _,typ := findParam(p.Params,ParamValue);strings.EqualFold(typ,"text"){
+		return &DateAndOrTime{Text:v},nil
+	}
+	d,err := ParseDateAndOrTime(v)
+	if err != nil{
+		return nil,err
+	}
+	return &d,nil
+}
+
+func (c Card) setDateAndOrTime(k string,d DateAndOrTime)  {
+	p := &Property{Name:k,Value:[][]string{{d.String()}}}
+	if d.Text != ""{
+		p.SetParam(ParamValue,"text")
+	}
+	c.Set(k,p)
+}
+
+/*
+return the BDAY of the card,nil if it has no birthday
+ */
+func (c Card) Birthday() (*DateAndOrTime,error) {
+	return propDateAndOrTime(c.Get(PropBday))
+}
+
+func (c Card) SetBirthday(d DateAndOrTime)  {
+	c.setDateAndOrTime(PropBday,d)
+}
+
+/*
+return the ANNIVERSARY of the card,nil if it has no anniversary
+ */
+func (c Card) Anniversary() (*DateAndOrTime,error) {
+	return propDateAndOrTime(c.Get(PropAnniversary))
+}
+
+func (c Card) SetAnniversary(d DateAndOrTime)  {
+	c.setDateAndOrTime(PropAnniversary,d)
+}
@@ -0,0 +1,112 @@
+package go_vcard
+
+import (
+	"testing"
+	"time"
+)
+
+var dateAndOrTimeTests = []struct {
+	s        string
+	basic    string
+	extended string
+	complete bool
+}{
+	{"19960415", "19960415", "1996-04-15", true},
+	{"1996-04-15", "19960415", "1996-04-15", true},
+	{"1985-04", "1985-04", "1985-04", false},
+	{"1996", "1996", "1996", false},
+	{"--0415", "--0415", "--04-15", false},
+	{"--04-15", "--0415", "--04-15", false},
+	{"--12", "--12", "--12", false},
+	{"---15", "---15", "---15", false},
+	{"T1022", "T1022", "T10:22", false},
+	{"T102200Z", "T102200Z", "T10:22:00Z", false},
+	{"T-2200", "T-2200", "T-22:00", false},
+	{"T--00", "T--00", "T--00", false},
+	{"19961022T140000-0500", "19961022T140000-0500", "1996-10-22T14:00:00-05:00", true},
+	{"1996-10-22T14:00:00+05:30", "19961022T140000+0530", "1996-10-22T14:00:00+05:30", true},
+	{"--0415T10", "--0415T10", "--04-15T10", false},
+}
+
+func TestParseDateAndOrTime(t *testing.T) {
+	for _, test := range dateAndOrTimeTests {
+		d, err := ParseDateAndOrTime(test.s)
+		if err != nil {
+			t.Errorf("ParseDateAndOrTime(%q): unexpected error: %v", test.s, err)
+			continue
+		}
+		if s := d.String(); s != test.basic {
+			t.Errorf("ParseDateAndOrTime(%q).String() = %q, want %q", test.s, s, test.basic)
+		}
+		if s := d.Extended(); s != test.extended {
+			t.Errorf("ParseDateAndOrTime(%q).Extended() = %q, want %q", test.s, s, test.extended)
+		}
+		if d.IsComplete() != test.complete {
+			t.Errorf("ParseDateAndOrTime(%q).IsComplete() = %v", test.s, d.IsComplete())
+		}
+		if _, err := d.Time(nil); (err == nil) != test.complete {
+			t.Errorf("ParseDateAndOrTime(%q).Time(): unexpected error %v", test.s, err)
+		}
+	}
+
+	for _, s := range []string{"", "199604", "19961315", "--13", "T25", "T1", "1996T", "T10+5", "circa 1800"} {
+		if _, err := ParseDateAndOrTime(s); err == nil {
+			t.Errorf("ParseDateAndOrTime(%q): expected an error", s)
+		}
+	}
+
+	d, _ := ParseDateAndOrTime("19961022T140000-0500")
+	tm, err := d.Time(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(1996, time.October, 22, 19, 0, 0, 0, time.UTC); !tm.Equal(expected) {
+		t.Errorf("Expected %v but got %v", expected, tm)
+	}
+	if s := NewDateAndOrTime(tm.UTC()).String(); s != "19961022T190000Z" {
+		t.Errorf("Expected 19961022T190000Z but got %q", s)
+	}
+}
+
+func TestCard_Birthday(t *testing.T) {
+	card := make(Card)
+	if d, err := card.Birthday(); err != nil || d != nil {
+		t.Errorf("Expected no birthday for an empty card, got %v, %v", d, err)
+	}
+
+	card.SetBirthday(NewDate(1996, time.April, 15))
+	if v := card.Get(PropBday).GetValueFirstText(); v != "19960415" {
+		t.Errorf("Expected BDAY 19960415 but got %q", v)
+	}
+	if d, err := card.Birthday(); err != nil {
+		t.Fatal("Expected no error when getting birthday, got:", err)
+	} else if tm, _ := d.Time(nil); !tm.Equal(time.Date(1996, time.April, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected birthday 1996-04-15 but got %v", tm)
+	}
+
+	card.SetBirthday(DateAndOrTime{Text: "circa 1800"})
+	if p := card.Get(PropBday); p.GetFirstParamVal(ParamValue) != "text" {
+		t.Errorf("Expected VALUE=text but got %v", p.Params)
+	}
+	if d, err := card.Birthday(); err != nil || !d.IsText() || d.Text != "circa 1800" {
+		t.Errorf("Expected text birthday but got %+v, %v", d, err)
+	}
+
+	card.SetValue(PropAnniversary, [][]string{{"--0415"}})
+	if d, err := card.Anniversary(); err != nil {
+		t.Fatal("Expected no error when getting anniversary, got:", err)
+	} else if d.HasYear || d.Month != 4 || d.Day != 15 {
+		t.Errorf("Expected anniversary --0415 but got %+v", d)
+	}
+	card.SetValue(PropAnniversary, [][]string{{"tomorrow"}})
+	if _, err := card.Anniversary(); err == nil {
+		t.Error("Expected an error for an invalid anniversary")
+	}
+
+	card.SetValue(PropRev, [][]string{{"1995-10-31T22:27:10Z"}})
+	if rev, err := card.Revision(); err != nil {
+		t.Fatal("Expected no error when getting an extended revision, got:", err)
+	} else if !rev.Equal(time.Date(1995, time.October, 31, 22, 27, 10, 0, time.UTC)) {
+		t.Errorf("Expected revision 1995-10-31T22:27:10Z but got %v", rev)
+	}
+}
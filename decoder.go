@@ -1,159 +1,533 @@
 package go_vcard
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
 	"io"
-	"text/scanner"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strings"
+	"unicode/utf8"
 )
 
+/*
+ParseError describe a structural problem of the input,
+Line and Column are 1-based and point into Raw,the unfolded content line
+ */
+type ParseError struct {
+	Line int
+	Column int
+	Raw string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	if e.Raw == ""{
+		return fmt.Sprintf("vcard:line %d,column %d:%s",e.Line,e.Column,e.Reason)
+	}
+	return fmt.Sprintf("vcard:line %d,column %d:%s:%q",e.Line,e.Column,e.Reason,e.Raw)
+}
+
 type Decoder struct {
-	scan *scanner.Scanner
+	/*
+	CharsetReader,if non-nil,is used to convert values with a CHARSET param
+	that the decoder does not know (UTF-8,US-ASCII,ISO-8859-1 and
+	Windows-1252 are built in),e.g. Shift_JIS from old vCard 2.1 exports.
+	it has the same signature as encoding/xml Decoder.CharsetReader
+	 */
+	CharsetReader func(charset string,input io.Reader) (io.Reader,error)
+
+	/*
+	Lossless record the position and the text of every property,an Encoder
+	then write the properties in their original order and write back the
+	original text of the properties that are not changed
+	 */
+	Lossless bool
+
+	r *bufio.Reader
+	line int //number of physical lines read
+	next string //physical line read ahead for unfolding
+	nextLineNo int
+	hasNext bool
+	rawLine string //last logical line returned by readLine
+	rawLineNo int
+	version string //version of the card being read
+	seq int //number of properties read in the card
 }
 
 func NewDecoder(r io.Reader) *Decoder {
-	var s scanner.Scanner
-	s.Init(r)
-	return &Decoder{&s}
+	return &Decoder{r:bufio.NewReader(r)}
 }
 
+/*
+Decode the next card of the stream,BEGIN:VCARD and END:VCARD are consumed as
+framing and not stored in the card.
+return io.EOF when there is no more card
+ */
 func (dc *Decoder) Decode() (Card,error) {
-	c := make(Card)
-	prop := dc.ReadProp()
-	for prop != nil{
-		c[prop.Name] = append(c[prop.Name],prop)
-		prop = dc.ReadProp()
+	var c Card
+	for{
+		prop,err := dc.ReadProp()
+		if err == io.EOF{
+			if c == nil{
+				return nil,io.EOF
+			}
+			return nil,&ParseError{Line:dc.line+1,Column:1,Reason:"unexpected end of input,missing END:VCARD"}
+		}
+		if err != nil{
+			return nil,err
+		}
+		isBegin := strings.EqualFold(prop.Name,PropBegin)
+		isEnd := strings.EqualFold(prop.Name,PropEnd)
+		if isBegin || isEnd{
+			if !strings.EqualFold(prop.GetValueFirstText(),"VCARD"){
+				return nil,dc.lineError(len(dc.rawLine)+1,"unknown component "+prop.GetValueFirstText())
+			}
+		}
+		switch {
+		case isBegin:
+			if c != nil{
+				return nil,dc.lineError(1,"BEGIN:VCARD inside a card,missing END:VCARD")
+			}
+			c = make(Card)
+		case isEnd:
+			if c == nil{
+				return nil,dc.lineError(1,"END:VCARD without BEGIN:VCARD")
+			}
+			return c,nil
+		case c == nil:
+			return nil,dc.lineError(1,"missing BEGIN:VCARD")
+		default:
+			c[prop.Name] = append(c[prop.Name],prop)
+		}
 	}
-	return c,nil
 }
 
-func (dc *Decoder) ReadProp() *Property {
-	if dc.scan.Peek() == scanner.EOF{
-		return nil
+/*
+read next property,return io.EOF when there is no more content line.
+vCard 2.1 values are decoded to UTF-8 and bare params are moved to TYPE
+ */
+func (dc *Decoder) ReadProp() (*Property,error) {
+	line,err := dc.readLine()
+	if err != nil{
+		return nil,err
+	}
+	prop,i,err := dc.parseHead(line)
+	if err != nil{
+		return nil,err
+	}
+	if dc.version == "2.1"{
+		normalizeBareParams(prop.Params)
+	}
+	encKey,enc := findParam(prop.Params,paramEncoding)
+	qp := strings.EqualFold(enc,"QUOTED-PRINTABLE")
+	raw := line
+	if qp{
+		//soft line break,the value go on in next physical line
+		for strings.HasSuffix(line,"="){
+			raw = ""
+			next,_,err := dc.readPhysicalLine()
+			if err == io.EOF{
+				break
+			}
+			if err != nil{
+				return nil,err
+			}
+			line = line[:len(line)-1]+next
+		}
+		dc.rawLine = line
+	}
+	csKey,charset := findParam(prop.Params,paramCharset)
+
+	if dc.version == "2.1"{
+		prop.Value = parseValuesSep(line[i+1:],";")
+	}else{
+		prop.Value = parseValues(line[i+1:])
+	}
+	if qp || charset != ""{
+		for _,vals := range prop.Value{
+			for vi,v := range vals{
+				if vals[vi],err = dc.decodeText(v,qp,charset);err != nil{
+					return nil,err
+				}
+			}
+		}
+		if qp{
+			delete(prop.Params,encKey)
+		}
+		delete(prop.Params,csKey)
+	}
+
+	switch {
+	case strings.EqualFold(prop.Name,PropBegin):
+		dc.version = ""
+		dc.seq = 0
+	case strings.EqualFold(prop.Name,PropVersion):
+		dc.version = prop.GetValueFirstText()
 	}
-	group,name := dc.readGroupName()
-	params := make(map[string][]string)
-	if dc.scan.Peek() == ';'{
-		params = dc.readParams()
+	if dc.Lossless{
+		dc.seq++
+		prop.src = &source{seq:dc.seq,line:raw,version:dc.version,decoded:prop.copy()}
 	}
-	dc.scan.Next()
-	value := dc.readValues()
-	return &Property{Group:group,Name:name,Params:params,Value:value}
+	return prop,nil
 }
 
-func (dc *Decoder) readGroupName() (group,name string) {
-	c := dc.scan.Peek()
-	var buf []rune
-	for c != scanner.EOF{
-		if c == '.'{
-			group = string(buf)
-			buf = []rune{}
+func (dc *Decoder) lineError(col int,reason string) *ParseError {
+	return &ParseError{Line:dc.rawLineNo,Column:col,Raw:dc.rawLine,Reason:reason}
+}
+
+/*
+read one physical line without line break,return the line and its number
+ */
+func (dc *Decoder) readPhysicalLine() (string,int,error) {
+	if dc.hasNext{
+		dc.hasNext = false
+		return dc.next,dc.nextLineNo,nil
+	}
+	l,err := dc.r.ReadString('\n')
+	if err == io.EOF && l != ""{
+		err = nil
+	}
+	if err != nil{
+		return "",0,err
+	}
+	dc.line++
+	l = strings.TrimSuffix(l,"\n")
+	l = strings.TrimSuffix(l,"\r")
+	return l,dc.line,nil
+}
+
+/*
+read one logical line,unfold the continuation lines and skip empty lines
+ */
+func (dc *Decoder) readLine() (string,error) {
+	var l string
+	var n int
+	var err error
+	for l == ""{
+		l,n,err = dc.readPhysicalLine()
+		if err != nil{
+			return "",err
+		}
+	}
+	dc.rawLineNo = n
+	//a large value has many continuation lines,they are appended to b
+	//instead of l to unfold in linear time
+	var b strings.Builder
+	for{
+		next,n,err := dc.readPhysicalLine()
+		if err == io.EOF{
+			break
+		}
+		if err != nil{
+			return "",err
+		}
+		if next != "" && (next[0] == ' ' || next[0] == '\t'){
+			//unfold
+			if b.Len() == 0{
+				b.WriteString(l)
+			}
+			b.WriteString(next[1:])
+			continue
+		}
+		dc.next,dc.nextLineNo = next,n
+		dc.hasNext = true
+		break
+	}
+	if b.Len() > 0{
+		l = b.String()
+	}
+	dc.rawLine = l
+	return l,nil
+}
+
+/*
+parse group,name and params of content line l,return the property and the
+index of the ':' that start the value
+ */
+func (dc *Decoder) parseHead(l string) (*Property,int,error) {
+	group,name,i := "","",0
+	for ;i < len(l);i++{
+		c := l[i]
+		if c == '.' && group == ""{
+			group = l[:i]
 		}else if c == ';' || c == ':'{
-			name = string(buf)
-			return
-		}else if c == '\n' || c == '\r'{
-			//skip empty line in vcard
-		}else{
-			buf = append(buf,c)
+			break
 		}
-		dc.scan.Next()
-		c = dc.scan.Peek()
 	}
-	return
+	if i == len(l){
+		return nil,i,dc.lineError(i+1,"missing ':' in content line")
+	}
+	if group != ""{
+		name = l[len(group)+1:i]
+	}else{
+		name = l[:i]
+	}
+	if name == ""{
+		return nil,i,dc.lineError(i+1,"empty property name")
+	}
+	var params map[string][]string
+	if l[i] == ';'{
+		var err error
+		params,i,err = dc.parseParams(l,i+1)
+		if err != nil{
+			return nil,i,err
+		}
+	}
+	return &Property{Group:group,Name:name,Params:params},i,nil
 }
 
-func (dc *Decoder) readParams() (params map[string][]string) {
-	lastChar := dc.scan.Peek()
-	c := lastChar
-	var buf []rune
-	var name string
-	var value string
+/*
+parse params start at l[i],return index of ':' that end the params.
+a param value may be quoted by DQUOTE and is caret-decoded (RFC 6868)
+ */
+func (dc *Decoder) parseParams(l string,i int) (params map[string][]string,end int,err error) {
 	params = make(map[string][]string)
+	var name string
 	var values []string
-	for c != scanner.EOF{
-		if c == ','{
-			values = append(values,string(buf))
-			buf = []rune{}
-		} else if c == ';' || c == ':'{
+	var buf []byte
+	quoted := false
+	quoteStart := 0
+	for ;i < len(l);i++{
+		c := l[i]
+		if quoted{
+			if c == '"'{
+				quoted = false
+			}else{
+				buf = append(buf,c)
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			quoted = true
+			quoteStart = i
+		case '=':
+			if name == ""{
+				name = string(buf)
+				buf = buf[:0]
+			}else{
+				buf = append(buf,c)
+			}
+		case ',':
+			values = append(values,dc.paramValue(buf))
+			buf = buf[:0]
+		case ';',':':
 			if name == ""{
 				name = string(buf)
-			} else {
-				value = string(buf)
-			}
-			if name != ""{
-				values = append(values,value)
-				if _,ok := params[name];ok{
-					params[name] = append(params[name],values...)
-				} else {
-					params[name] = values
+				values = append(values,"")
+			}else{
+				values = append(values,dc.paramValue(buf))
+			}
+			if name == ""{
+				return nil,i,dc.lineError(i+1,"empty parameter name")
+			}
+			if strings.EqualFold(name,ParamType){
+				//TYPE="work,voice" is a list of types
+				var types []string
+				for _,v := range values{
+					types = append(types,strings.Split(v,",")...)
 				}
+				values = types
 			}
+			params[name] = append(params[name],values...)
 			if c == ':'{
-				return
+				return params,i,nil
 			}
-			buf = []rune{}
-			values = []string{}
 			name = ""
-			value = ""
-		} else if c == '='{
-			name = string(buf)
-			buf = []rune{}
-		}else{
+			values = nil
+			buf = buf[:0]
+		default:
 			buf = append(buf,c)
 		}
-		dc.scan.Next()
-		c = dc.scan.Peek()
 	}
-	return 
+	if quoted{
+		return nil,i,dc.lineError(quoteStart+1,"unterminated quoted parameter value")
+	}
+	return nil,i,dc.lineError(i+1,"unterminated parameters,missing ':'")
 }
 
-func (dc *Decoder) readValues() (values [][]string) {
-	lastChar := dc.scan.Next()
-	c := lastChar
-	var buf []rune
-	escape := false
-	var val []string
-	for c != scanner.EOF{
-		if c == '\n'{
-			la := dc.scan.Peek()
-			if la != 32 && la != 9{
-				if len(buf) > 0{
-					val = append(val,string(buf))
-				}
-				values = append(values,val)
-				return
-			}else{
-				//unfold
-				lastChar = la
-				c = dc.scan.Next()
-				for c == 32 || c == 9{
-					c = dc.scan.Next()
-				}
+/*
+decode the ^n,^^ and ^' of a param value (RFC 6868),vCard 2.1 has no escaping
+ */
+func (dc *Decoder) paramValue(b []byte) string {
+	if dc.version == "2.1" || bytes.IndexByte(b,'^') < 0{
+		return string(b)
+	}
+	buf := make([]byte,0,len(b))
+	for i := 0;i < len(b);i++{
+		if b[i] == '^' && i+1 < len(b){
+			switch b[i+1] {
+			case 'n','N':
+				buf = append(buf,'\n')
+				i++
+				continue
+			case '^':
+				buf = append(buf,'^')
+				i++
+				continue
+			case '\'':
+				buf = append(buf,'"')
+				i++
+				continue
 			}
 		}
-		if c == '\\'{
-			escape = true
-		}else if escape{
+		buf = append(buf,b[i])
+	}
+	return string(buf)
+}
+
+/*
+parse value,first seperate by ';',secondly seperate by ','
+ */
+func parseValues(s string) [][]string {
+	return parseValuesSep(s,";,")
+}
+
+/*
+parse value,';' in sep seperate the components and ',' in sep seperate the
+list values of a component
+ */
+func parseValuesSep(s string,sep string) (values [][]string) {
+	splitList := strings.IndexByte(sep,',') >= 0
+	var buf []byte
+	var val []string
+	escape := false
+	for i := 0;i < len(s);i++{
+		c := s[i]
+		if escape{
 			if c == 'n' || c == 'N'{
 				c = '\n'
 			}
 			buf = append(buf,c)
 			escape = false
-		}else if c == ','{
-			if len(buf) > 0{
-				val = append(val,string(buf))
-				buf = []rune{}
-			}
+		}else if c == '\\'{
+			escape = true
+		}else if c == ',' && splitList{
+			val = append(val,string(buf))
+			buf = buf[:0]
 		}else if c == ';'{
-			if len(buf) > 0{
-				val = append(val,string(buf))
-				buf = []rune{}
-			}
+			val = append(val,string(buf))
+			buf = buf[:0]
 			values = append(values,val)
-			val = []string{}
-		}else if c != '\n' && c != '\r'{
+			val = nil
+		}else{
 			buf = append(buf,c)
 		}
-		lastChar = c
-		c = dc.scan.Next()
 	}
+	val = append(val,string(buf))
+	values = append(values,val)
 	return
 }
+
+const (
+	paramEncoding = "ENCODING"
+	paramCharset = "CHARSET"
+)
+
+/*
+find a param ignore case,return the key used in params and its first value
+ */
+func findParam(params map[string][]string,name string) (key string,val string) {
+	for k,vals := range params{
+		if strings.EqualFold(k,name){
+			if len(vals) > 0{
+				val = vals[0]
+			}
+			return k,val
+		}
+	}
+	return "",""
+}
+
+/*
+vCard 2.1 allow params without name,e.g. TEL;CELL;PREF:,
+move them to TYPE or to the param they are a value of
+ */
+func normalizeBareParams(params map[string][]string) {
+	for k,vals := range params{
+		bare := true
+		for _,v := range vals{
+			if v != ""{
+				bare = false
+				break
+			}
+		}
+		if !bare{
+			continue
+		}
+		var name string
+		switch strings.ToUpper(k) {
+		case ParamType,ParamValue,ParamLanguage,paramEncoding,paramCharset:
+			continue
+		case "7BIT","8BIT","QUOTED-PRINTABLE","BASE64":
+			name = paramEncoding
+		case "INLINE","URL","CONTENT-ID","CID":
+			name = ParamValue
+		default:
+			name = ParamType
+		}
+		delete(params,k)
+		if key,_ := findParam(params,name);key != ""{
+			name = key
+		}
+		params[name] = append(params[name],k)
+	}
+}
+
+/*
+decode a vCard 2.1 text value to UTF-8
+ */
+func (dc *Decoder) decodeText(s string,qp bool,charset string) (string,error) {
+	b := []byte(s)
+	if qp{
+		var err error
+		b,err = ioutil.ReadAll(quotedprintable.NewReader(bytes.NewReader(b)))
+		if err != nil{
+			return "",dc.lineError(1,"invalid quoted-printable value:"+err.Error())
+		}
+	}
+	switch strings.ToUpper(charset) {
+	case "","UTF-8","US-ASCII":
+		return string(b),nil
+	case "ISO-8859-1","LATIN1":
+		return decodeSingleByte(b,nil),nil
+	case "WINDOWS-1252","CP1252":
+		return decodeSingleByte(b,&windows1252),nil
+	}
+	if dc.CharsetReader == nil{
+		return "",dc.lineError(1,"unsupported charset "+charset)
+	}
+	r,err := dc.CharsetReader(charset,bytes.NewReader(b))
+	if err != nil{
+		return "",dc.lineError(1,"charset "+charset+":"+err.Error())
+	}
+	b,err = ioutil.ReadAll(r)
+	if err != nil{
+		return "",dc.lineError(1,"charset "+charset+":"+err.Error())
+	}
+	return string(b),nil
+}
+
+/*
+decode single byte charset,table replace the 0x80-0x9F range of ISO-8859-1
+ */
+func decodeSingleByte(b []byte,table *[32]rune) string {
+	buf := make([]byte,0,len(b))
+	for _,c := range b{
+		r := rune(c)
+		if table != nil && c >= 0x80 && c < 0xA0{
+			r = table[c-0x80]
+		}
+		buf = utf8.AppendRune(buf,r)
+	}
+	return string(buf)
+}
+
+var windows1252 = [32]rune{
+	'\u20AC','\u0081','\u201A','\u0192','\u201E','\u2026','\u2020','\u2021',
+	'\u02C6','\u2030','\u0160','\u2039','\u0152','\u008D','\u017D','\u008F',
+	'\u0090','\u2018','\u2019','\u201C','\u201D','\u2022','\u2013','\u2014',
+	'\u02DC','\u2122','\u0161','\u203A','\u0153','\u009D','\u017E','\u0178',
+}
@@ -0,0 +1,214 @@
+package go_vcard
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCardsStream = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Akiyama Mio\r\n" +
+	"END:VCARD\r\n" +
+	"\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Hirasawa Yui\r\n" +
+	"EMAIL:yui@example.com\r\n" +
+	"END:VCARD"
+
+func TestDecoder_multiple(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(testCardsStream))
+
+	expected := []string{"Akiyama Mio", "Hirasawa Yui"}
+	for _, fn := range expected {
+		card, err := dec.Decode()
+		if err != nil {
+			t.Fatal("Expected no error when decoding card, got:", err)
+		}
+		if v := card.Get(PropFN).GetValueFirstText(); v != fn {
+			t.Errorf("Expected FN to be %q but got %q", fn, v)
+		}
+		if _, ok := card[PropBegin]; ok {
+			t.Errorf("Expected BEGIN not to be stored in card")
+		}
+		if _, ok := card[PropEnd]; ok {
+			t.Errorf("Expected END not to be stored in card")
+		}
+	}
+
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of stream but got %v", err)
+	}
+}
+
+func TestDecoder_parseError(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"BEGIN:VCARD\r\nVERSION:4.0\r\nFN Joe Bloggs\r\nEND:VCARD\r\n", 3},
+		{"VERSION:4.0\r\nFN:Joe Bloggs\r\nEND:VCARD\r\n", 1},
+		{"END:VCARD\r\n", 1},
+		{"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Joe Bloggs\r\n", 4},
+		{"BEGIN:VCARD\r\nVERSION:4.0\r\nEMAIL;TYPE=home\r\nEND:VCARD\r\n", 3},
+		{"BEGIN:VCARD\r\n:Joe Bloggs\r\nEND:VCARD\r\n", 2},
+	}
+
+	for _, test := range tests {
+		_, err := NewDecoder(strings.NewReader(test.input)).Decode()
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Expected a *ParseError when decoding %q but got %v", test.input, err)
+			continue
+		}
+		if perr.Line != test.line {
+			t.Errorf("Expected error at line %d when decoding %q but got line %d (%v)", test.line, test.input, perr.Line, perr)
+		}
+	}
+}
+
+func TestDecoder_v21(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=C3=B6rg;;;\r\n" +
+		"NOTE;ENCODING=QUOTED-PRINTABLE:first line=0D=0A=\r\n" +
+		"second line\r\n" +
+		"ADR;HOME;CHARSET=ISO-8859-1:;;Stra\xdfe 1;K\xf6ln;;;Germany\r\n" +
+		"TEL;CELL;PREF:+49 170 1234567\r\n" +
+		"END:VCARD\r\n"
+
+	card, err := NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding vCard 2.1, got:", err)
+	}
+
+	if n := card.Get(PropN); n.Value[0][0] != "Müller" || n.Value[1][0] != "Jörg" {
+		t.Errorf("Expected N to be decoded but got %q", n.Value)
+	} else if len(n.Params) != 0 {
+		t.Errorf("Expected ENCODING and CHARSET to be removed but got %v", n.Params)
+	}
+	if v := card.Get(PropNote).GetValueFirstText(); v != "first line\r\nsecond line" {
+		t.Errorf("Expected NOTE soft line break to be joined but got %q", v)
+	}
+	adr := card.Get(PropAdr)
+	if adr.Value[2][0] != "Straße 1" || adr.Value[3][0] != "Köln" {
+		t.Errorf("Expected ADR to be decoded from ISO-8859-1 but got %q", adr.Value)
+	}
+	if !adr.IsHasType("home") {
+		t.Errorf("Expected ADR to have TYPE=HOME but got %v", adr.Params)
+	}
+	tel := card.Get(PropTel)
+	if !tel.IsHasType("cell") || !tel.IsHasType("pref") {
+		t.Errorf("Expected TEL to have TYPE=CELL,PREF but got %v", tel.Params)
+	}
+}
+
+func TestDecoder_charsetReader(t *testing.T) {
+	input := "BEGIN:VCARD\r\nVERSION:2.1\r\nFN;CHARSET=X-TEST:abc\r\nEND:VCARD\r\n"
+
+	if _, err := NewDecoder(strings.NewReader(input)).Decode(); err == nil {
+		t.Error("Expected an error for an unknown charset")
+	}
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return strings.NewReader("xyz"), nil
+	}
+	card, err := dec.Decode()
+	if err != nil {
+		t.Fatal("Expected no error with a CharsetReader, got:", err)
+	}
+	if v := card.Get(PropFN).GetValueFirstText(); v != "xyz" {
+		t.Errorf("Expected FN to be converted by CharsetReader but got %q", v)
+	}
+}
+
+func TestDecoder_quotedParams(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"ADR;LABEL=\"123 Main St;Apt 4^nSpringfield\";GEO=\"geo:1,2\";TZ=\"America/New_York\":;;123 Main St;Springfield;;;\r\n" +
+		"TEL;VALUE=uri;TYPE=\"voice,home\":tel:+1-555-555-5555\r\n" +
+		"FN;X-NICK=^'Ace^' ^^:Joe Bloggs\r\n" +
+		"END:VCARD\r\n"
+
+	card, err := NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding quoted params, got:", err)
+	}
+
+	adr := card.Get(PropAdr)
+	expected := map[string]string{
+		"LABEL":  "123 Main St;Apt 4\nSpringfield",
+		ParamGEO: "geo:1,2",
+		ParamTZ:  "America/New_York",
+	}
+	for k, v := range expected {
+		if got := adr.Params[k]; len(got) != 1 || got[0] != v {
+			t.Errorf("Expected ADR param %s to be %q but got %q", k, v, got)
+		}
+	}
+	if tel := card.Get(PropTel); !tel.IsHasType("voice") || !tel.IsHasType("home") {
+		t.Errorf("Expected TEL types voice and home but got %q", tel.Params[ParamType])
+	}
+	if v := card.Get(PropFN).GetFirstParamVal("X-NICK"); v != `"Ace" ^` {
+		t.Errorf("Expected caret-decoded param but got %q", v)
+	}
+
+	if _, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\nFN;X-A=\"abc:def\r\nEND:VCARD\r\n")).Decode(); err == nil {
+		t.Error("Expected an error for an unterminated quoted param")
+	}
+}
+
+/*
+a card with a NOTE of n folded lines of 74 octets
+ */
+func foldedCard(n int) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Joe\r\nNOTE:")
+	b.WriteString(strings.Repeat("a", 69))
+	for i := 0; i < n; i++ {
+		b.WriteString("\r\n ")
+		b.WriteString(strings.Repeat("b", 74))
+	}
+	b.WriteString("\r\nEND:VCARD\r\n")
+	return b.String()
+}
+
+func TestDecoder_largeFoldedValue(t *testing.T) {
+	//about 4 MB
+	const lines = 55000
+	input := foldedCard(lines)
+
+	type result struct {
+		card Card
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		card, err := NewDecoder(strings.NewReader(input)).Decode()
+		done <- result{card, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal("Expected no error when decoding a large folded value, got:", r.err)
+		}
+		if n := len(r.card.Get(PropNote).GetValueFirstText()); n != 69+74*lines {
+			t.Errorf("Expected a NOTE of %d octets but got %d", 69+74*lines, n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a large folded value to be unfolded in linear time")
+	}
+}
+
+func BenchmarkDecoder_largeFoldedValue(b *testing.B) {
+	input := foldedCard(40000)
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		if _, err := NewDecoder(strings.NewReader(input)).Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
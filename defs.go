@@ -795,6 +795,41 @@ const(
 	KindLocation = "location"
 )
 
+//TYPE param values of TEL
+const (
+	TelVoice = "voice"
+	TelCell = "cell"
+	TelFax = "fax"
+	TelText = "text"
+	TelVideo = "video"
+	TelPager = "pager"
+	TelTextPhone = "textphone"
+)
+
+//TYPE param values of RELATED
+const (
+	RelatedContact = "contact"
+	RelatedAcquaintance = "acquaintance"
+	RelatedFriend = "friend"
+	RelatedMet = "met"
+	RelatedCoWorker = "co-worker"
+	RelatedColleague = "colleague"
+	RelatedCoResident = "co-resident"
+	RelatedNeighbor = "neighbor"
+	RelatedChild = "child"
+	RelatedParent = "parent"
+	RelatedSibling = "sibling"
+	RelatedSpouse = "spouse"
+	RelatedKin = "kin"
+	RelatedMuse = "muse"
+	RelatedCrush = "crush"
+	RelatedDate = "date"
+	RelatedSweetheart = "sweetheart"
+	RelatedMe = "me"
+	RelatedAgent = "agent"
+	RelatedEmergency = "emergency"
+)
+
 const (
 	SexUnspecified  = ""
 	SexFemale       = "F"
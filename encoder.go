@@ -1,92 +1,496 @@
 package go_vcard
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
+/*
+EncodeOptions control the output of an Encoder
+ */
+type EncodeOptions struct {
+	/*
+	Version of the output,"4.0"(default),"3.0"(RFC 2426) or "2.1".
+	the card is not changed,only its encoding follow the version:
+	TYPE=PREF instead of PREF=1,inline base64 media instead of data: URI,
+	LABEL property instead of ADR LABEL param and,for 2.1,
+	QUOTED-PRINTABLE for non-ASCII values
+	 */
+	Version string
+
+	/*
+	DisableFolding write every content line on a single line,for the
+	systems that can not unfold
+	 */
+	DisableFolding bool
+
+	/*
+	Canonical write property and param names in upper case and merge the
+	params that differ only by case,so that equal cards are encoded
+	to the same bytes
+	 */
+	Canonical bool
+
+	/*
+	ParamOrder is the order params are written in,the params not listed
+	follow in alphabetical order.nil means DefaultParamOrder
+	 */
+	ParamOrder []string
+
+	/*
+	MaxBytes,if positive,is the size budget of each encoded card,e.g. for
+	a QR code.optional properties are dropped in the order of DropOrder
+	until the card fits,FN,N and VERSION are never dropped
+	 */
+	MaxBytes int
+
+	/*
+	DropOrder is the order properties are dropped in to fit MaxBytes,the
+	properties not listed are dropped first.nil means DefaultDropOrder
+	 */
+	DropOrder []string
+}
+
+//params written first unless EncodeOptions.ParamOrder is set
+var DefaultParamOrder = []string{ParamType,ParamPref}
+
+//properties dropped to fit EncodeOptions.MaxBytes,least useful first
+var DefaultDropOrder = []string{
+	PropPhoto,PropLogo,PropSound,PropKey,PropNote,PropCategories,PropGEO,PropTZ,
+	PropAnniversary,PropBday,PropNickName,PropRole,PropImpp,PropAdr,PropTiTle,
+	PropUrl,PropOrg,PropEmail,PropTel,
+}
+
+//properties never dropped to fit EncodeOptions.MaxBytes
+var requiredProps = map[string]bool{PropVersion:true,PropFN:true,PropN:true}
+
+//max octets of a content line,without CRLF
+const maxLineLength = 75
+
+//max octets of a QUOTED-PRINTABLE line,without CRLF
+const maxQuotedPrintableLine = 76
+
 type Encoder struct {
 	writer io.Writer
+	opts EncodeOptions
+	buf bytes.Buffer //output not yet written to writer
+	line bytes.Buffer //content line being written
 }
 
 func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{writer:w}
 }
+
+func NewEncoderWithOptions(w io.Writer,opts EncodeOptions) *Encoder {
+	return &Encoder{writer:w,opts:opts}
+}
+
+/*
+encode a card,the card is written to the underlying writer with a single
+Write and any error of it is returned
+ */
 func (ec *Encoder) Encode(c Card) error {
-	begin := "BEGIN:VCARD\r\n"
-	if _,err := io.WriteString(ec.writer,begin);err != nil{
+	ec.buf.Reset()
+	if err := ec.encode(c);err != nil{
 		return err
 	}
+	return ec.flush()
+}
+
+/*
+encode a stream of cards,nothing is written unless every card can be
+encoded,then the whole stream is written with a single Write
+ */
+func (ec *Encoder) EncodeAll(cards []Card) error {
+	ec.buf.Reset()
+	for i,c := range cards{
+		if err := ec.encode(c);err != nil{
+			ec.buf.Reset()
+			return fmt.Errorf("vcard:card %d:%v",i,err)
+		}
+	}
+	return ec.flush()
+}
+
+/*
+write the buffered output to the writer
+ */
+func (ec *Encoder) flush() error {
+	_,err := ec.writer.Write(ec.buf.Bytes())
+	ec.buf.Reset()
+	return err
+}
+
+/*
+encode a card to the buffer
+ */
+func (ec *Encoder) encode(c Card) error {
+	if ec.opts.MaxBytes > 0{
+		return ec.encodeCompact(c)
+	}
+	return ec.encodeCard(c)
+}
+
+/*
+encode a card to the buffer,dropping properties until it fits MaxBytes
+ */
+func (ec *Encoder) encodeCompact(c Card) error {
+	order := ec.opts.DropOrder
+	if order == nil{
+		order = DefaultDropOrder
+	}
+	start := ec.buf.Len()
+	c = copyCard(c)
+	for{
+		ec.buf.Truncate(start)
+		if err := ec.encodeCard(c);err != nil{
+			return err
+		}
+		if ec.buf.Len()-start <= ec.opts.MaxBytes{
+			return nil
+		}
+		if !dropProperty(c,order){
+			ec.buf.Truncate(start)
+			return fmt.Errorf("vcard:card does not fit in %d bytes",ec.opts.MaxBytes)
+		}
+	}
+}
+
+/*
+copy the card and its property slices,the properties are shared
+ */
+func copyCard(c Card) Card {
+	cp := make(Card,len(c))
+	for k,ps := range c{
+		cp[k] = append([]*Property(nil),ps...)
+	}
+	return cp
+}
+
+/*
+drop a single property:first the properties not in order,then those of
+order.the least preferred of a name is dropped first
+ */
+func dropProperty(c Card,order []string) bool {
+	rank := func(k string) int {
+		for i,o := range order{
+			if strings.EqualFold(k,o){
+				return i
+			}
+		}
+		return -1
+	}
+	key := ""
+	for k,ps := range c{
+		if len(ps) == 0 || requiredProps[strings.ToUpper(k)]{
+			continue
+		}
+		if key == "" || rank(k) < rank(key) || rank(k) == rank(key) && k < key{
+			key = k
+		}
+	}
+	if key == ""{
+		return false
+	}
+	ps := c[key]
+	i := len(ps)-1
+	if pref := c.Pref(key);ps[i] == pref && i > 0{
+		//keep the preferred one
+		for j := range ps{
+			if ps[j] != pref{
+				i = j
+			}
+		}
+	}
+	c[key] = append(ps[:i:i],ps[i+1:]...)
+	if len(c[key]) == 0{
+		delete(c,key)
+	}
+	return true
+}
+
+/*
+encode a card to the buffer as it is
+ */
+func (ec *Encoder) encodeCard(c Card) error {
+	switch ec.opts.Version {
+	case "","4.0","3.0","2.1":
+	default:
+		return fmt.Errorf("vcard:unsupported version %q",ec.opts.Version)
+	}
 	version := c.Get(PropVersion)
 	if version == nil{
 		return errors.New("VCARD: Version property missing")
 	}
-	ec.WriteProperty(version)
+	ec.buf.WriteString("BEGIN:VCARD\r\n")
+	if ec.isLegacy(){
+		version = &Property{Name:PropVersion,Value:[][]string{{ec.opts.Version}}}
+	}else if version.Name == ""{
+		//e.g. set by Card.SetValue
+		v := *version
+		v.Name = PropVersion
+		version = &v
+	}
 	var keys []string
 	for k := range c{
 		keys = append(keys,k)
 	}
 	sort.Strings(keys)
+	var props []*Property
+	ordered := false
 	for _,k := range keys{
-		props := c[k]
 		if strings.EqualFold(k,PropVersion){
 			continue
 		}
-		for _,p := range props{
-			ec.WriteProperty(p)
+		ps := c[k]
+		if ec.isLegacy(){
+			ps = legacyProps(c,k,ec.opts.Version)
+		}
+		for _,p := range ps{
+			ordered = ordered || p.src != nil
+			if p.Name == ""{
+				//e.g. added by Card.SetValue,the key is its name
+				q := *p
+				q.Name = k
+				p = &q
+			}
+			props = append(props,p)
+		}
+	}
+	if ordered{
+		//properties of a lossless decoded card are written in their original
+		//order,the properties added since then follow
+		if version.src != nil{
+			props = append(props,version)
 		}
+		sortBySource(props)
 	}
-	end := "END:VCARD\r\n"
-	io.WriteString(ec.writer,end)
+	if !ordered || version.src == nil{
+		ec.writeProperty(version)
+	}
+	for _,p := range props{
+		ec.writeProperty(p)
+	}
+	ec.buf.WriteString("END:VCARD\r\n")
 	return nil
 }
-func (ec *Encoder) WriteProperty(prop *Property)  {
+
+/*
+sort properties of a lossless decoded card in their original order,the
+properties that are not decoded follow in their current order
+ */
+func sortBySource(props []*Property)  {
+	sort.SliceStable(props,func(i,j int) bool {
+		si,sj := props[i].src,props[j].src
+		if si == nil || sj == nil{
+			return si != nil && sj == nil
+		}
+		return si.seq < sj.seq
+	})
+}
+
+/*
+the output is vCard 3.0 or 2.1
+ */
+func (ec *Encoder) isLegacy() bool {
+	return ec.opts.Version == "3.0" || ec.opts.Version == "2.1"
+}
+
+/*
+write a single content line
+ */
+func (ec *Encoder) WriteProperty(prop *Property) error {
+	ec.buf.Reset()
+	ec.writeProperty(prop)
+	return ec.flush()
+}
+
+func (ec *Encoder) writeProperty(prop *Property)  {
+	if src := prop.src;src != nil && src.line != "" && !ec.opts.Canonical &&
+		(ec.opts.Version == "" || ec.opts.Version == src.version) && prop.unchanged(){
+		ec.writeFolded([]byte(src.line))
+		return
+	}
+	v21 := ec.opts.Version == "2.1"
+	params := prop.Params
+	qp := v21 && needQuotedPrintable(prop.Value)
+	if qp{
+		params = copyParams(params)
+		params[paramEncoding] = []string{"QUOTED-PRINTABLE"}
+		params[paramCharset] = []string{"UTF-8"}
+	}
+	line := &ec.line
+	line.Reset()
 	if prop.Group != ""{
-		io.WriteString(ec.writer,prop.Group)
-		io.WriteString(ec.writer,".")
-	}
-	io.WriteString(ec.writer,prop.Name)
-	if prop.Params != nil{
-		for key,vals := range prop.Params{
-			io.WriteString(ec.writer,";")
-			io.WriteString(ec.writer,key)
+		line.WriteString(prop.Group)
+		line.WriteString(".")
+	}
+	if ec.opts.Canonical{
+		line.WriteString(strings.ToUpper(prop.Name))
+		params = upperParams(params)
+	}else{
+		line.WriteString(prop.Name)
+	}
+	if params != nil{
+		for _,key := range ec.sortParams(params){
+			vals := params[key]
+			if v21 && strings.EqualFold(key,ParamType){
+				//vCard 2.1 write types as bare params,e.g. TEL;CELL;PREF:
+				for _,v := range vals{
+					line.WriteString(";")
+					line.WriteString(strings.ToUpper(v))
+				}
+				continue
+			}
+			line.WriteString(";")
+			line.WriteString(key)
 			if len(vals) > 0{
-				io.WriteString(ec.writer,"=")
+				line.WriteString("=")
 				for vi := 0;vi < len(vals);vi++{
-					io.WriteString(ec.writer,vals[vi])
+					ec.writeParamValue(line,vals[vi])
 					if vi+1 < len(vals){
-						io.WriteString(ec.writer,",")
+						line.WriteString(",")
 					}
 				}
 			}
 		}
 	}
-	io.WriteString(ec.writer,":")
+	line.WriteString(":")
+	if qp{
+		//quoted-printable has its own soft line breaks,it is not folded
+		ec.writeQuotedPrintable(line,line.Len(),prop.Value)
+		line.WriteString("\r\n")
+		ec.buf.Write(line.Bytes())
+		return
+	}
 	for si:=0;si < len(prop.Value);si++{
 		for vi := 0; vi < len(prop.Value[si]);vi++{
-			ec.WriteValue(prop.Value[si][vi])
+			ec.writeValue(line,prop.Value[si][vi])
 			if vi+1 < len(prop.Value[si]){
-				io.WriteString(ec.writer,",")
+				line.WriteString(",")
 			}
 		}
 		if si+1 < len(prop.Value){
-			io.WriteString(ec.writer,";")
+			line.WriteString(";")
 		}
 	}
-	io.WriteString(ec.writer,"\r\n")
+	ec.writeFolded(line.Bytes())
 }
 
-func (ec *Encoder) WriteValue(val string)  {
-	i := 0
-	for _,c := range val{
-		if i == 76{
-			io.WriteString(ec.writer,"\n  ")
-			i = 0
+/*
+return the keys of params in the order of ParamOrder,then alphabetical
+ */
+func (ec *Encoder) sortParams(params map[string][]string) []string {
+	order := ec.opts.ParamOrder
+	if order == nil{
+		order = DefaultParamOrder
+	}
+	rank := func(k string) int {
+		for i,o := range order{
+			if strings.EqualFold(k,o){
+				return i
+			}
+		}
+		return len(order)
+	}
+	keys := make([]string,0,len(params))
+	for k := range params{
+		keys = append(keys,k)
+	}
+	sort.Slice(keys,func(i,j int) bool {
+		ri,rj := rank(keys[i]),rank(keys[j])
+		if ri != rj{
+			return ri < rj
 		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+/*
+copy params with upper case names,values of the same name are merged
+ */
+func upperParams(params map[string][]string) map[string][]string {
+	if params == nil{
+		return nil
+	}
+	up := make(map[string][]string,len(params))
+	for k,vals := range params{
+		k = strings.ToUpper(k)
+		up[k] = append(up[k],vals...)
+	}
+	return up
+}
+
+/*
+write a content line followed by CRLF,folded at 75 octets (RFC 6350 3.2)
+without splitting a multi-byte UTF-8 sequence
+ */
+func (ec *Encoder) writeFolded(line []byte)  {
+	if ec.opts.DisableFolding{
+		ec.buf.Write(line)
+		ec.buf.WriteString("\r\n")
+		return
+	}
+	max := maxLineLength
+	for len(line) > max{
+		n := max
+		for n > 0 && !utf8.RuneStart(line[n]){
+			n--
+		}
+		if n == 0{
+			//not UTF-8,cut at the limit so the loop always advance
+			n = max
+		}
+		ec.buf.Write(line[:n])
+		//the continuation line start with a single space
+		ec.buf.WriteString("\r\n ")
+		line = line[n:]
+		max = maxLineLength-1
+	}
+	ec.buf.Write(line)
+	ec.buf.WriteString("\r\n")
+}
+
+var caretEscaper = strings.NewReplacer("^","^^","\r\n","^n","\n","^n",`"`,"^'")
+
+/*
+write a param value caret-encoded (RFC 6868) and quoted when it contains
+':',';' or ','
+ */
+func (ec *Encoder) writeParamValue(w *bytes.Buffer,val string)  {
+	if ec.opts.Version == "2.1"{
+		w.WriteString(val)
+		return
+	}
+	val = caretEscaper.Replace(val)
+	if strings.ContainsAny(val,":;,"){
+		val = `"`+val+`"`
+	}
+	w.WriteString(val)
+}
+
+/*
+write an escaped value
+ */
+func (ec *Encoder) WriteValue(val string) error {
+	ec.buf.Reset()
+	ec.writeValue(&ec.buf,val)
+	return ec.flush()
+}
+
+func (ec *Encoder) writeValue(w *bytes.Buffer,val string)  {
+	v21 := ec.opts.Version == "2.1"
+	for _,c := range val{
 		var e string
 		switch c {
+		case '\\':
+			e = `\\`
 		case '\r':
 			e = `\r`
 		case '\n':
@@ -96,12 +500,108 @@ func (ec *Encoder) WriteValue(val string)  {
 		//case ':'://TODO:does this need?
 		//	e = `\:`
 		case ',':
-			e = `\,`
+			//vCard 2.1 does not escape comma
+			if v21{
+				e = ","
+			}else{
+				e = `\,`
+			}
 		default:
 			e = string(c)
-			
+
+		}
+		w.WriteString(e)
+	}
+}
+
+/*
+write a vCard 2.1 value as QUOTED-PRINTABLE,';' is escaped before encoding
+so the components are kept.
+col is the length of the NAME;PARAMS: header,the soft line breaks keep
+every line in 76 octets with the header
+ */
+func (ec *Encoder) writeQuotedPrintable(buf *bytes.Buffer,col int,vals [][]string)  {
+	var text strings.Builder
+	for si,comps := range vals{
+		if si > 0{
+			text.WriteString(";")
+		}
+		for vi,v := range comps{
+			if vi > 0{
+				text.WriteString(",")
+			}
+			v = strings.Replace(v,`\`,`\\`,-1)
+			text.WriteString(strings.Replace(v,";",`\;`,-1))
+		}
+	}
+	s := text.String()
+	for i := 0;i < len(s);i++{
+		c := s[i]
+		e := fmt.Sprintf("=%02X",c)
+		//white space at the end of the value is encoded
+		if c >= '!' && c <= '~' && c != '=' || (c == ' ' || c == '\t') && i+1 < len(s){
+			e = string(c)
+		}
+		//a soft line break takes the last octet of the line
+		if col+len(e) > maxQuotedPrintableLine-1{
+			buf.WriteString("=\r\n")
+			col = 0
+		}
+		buf.WriteString(e)
+		col += len(e)
+	}
+}
+
+/*
+vCard 2.1 text has to be QUOTED-PRINTABLE when it is not plain ASCII
+ */
+func needQuotedPrintable(vals [][]string) bool {
+	for _,comps := range vals{
+		for _,v := range comps{
+			for i := 0;i < len(v);i++{
+				if v[i] >= 0x80 || v[i] == '\n' || v[i] == '\r'{
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func copyParams(params map[string][]string) map[string][]string {
+	cp := make(map[string][]string,len(params))
+	for k,vals := range params{
+		cp[k] = append([]string(nil),vals...)
+	}
+	return cp
+}
+
+/*
+return the properties of key written in vCard 3.0 or 2.1 syntax,
+the properties of the card are not modified
+ */
+func legacyProps(c Card,key string,version string) []*Property {
+	pref := c.Pref(key)
+	var props []*Property
+	for _,p := range c[key]{
+		q := *p
+		q.Params = copyParams(p.Params)
+		if pk,_ := findParam(q.Params,ParamPref);pk != ""{
+			delete(q.Params,pk)
+			if p == pref && !q.IsHasType("pref"){
+				q.Params[ParamType] = append(q.Params[ParamType],"pref")
+			}
+		}
+		switch strings.ToUpper(q.Name) {
+		case PropPhoto,PropLogo,PropSound,PropKey:
+			legacyMedia(&q,version)
+		case PropAdr:
+			if lp := labelProperty(&q);lp != nil{
+				props = append(props,&q,lp)
+				continue
+			}
 		}
-		io.WriteString(ec.writer,e)
-		i++
+		props = append(props,&q)
 	}
+	return props
 }
@@ -2,8 +2,12 @@ package go_vcard
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 func TestEncoder(t *testing.T) {
@@ -40,3 +44,390 @@ var testValue = []struct {
 }
 
 
+
+func TestEncoder_version(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"FN":      []*Property{{Name: "FN", Value: [][]string{{"Jörg Müller"}}}},
+		"EMAIL": []*Property{
+			{Name: "EMAIL", Value: [][]string{{"joerg@example.org"}}, Params: map[string][]string{"TYPE": {"home"}}},
+			{Name: "EMAIL", Value: [][]string{{"joerg@example.com"}}, Params: map[string][]string{"TYPE": {"work"}, "PREF": {"1"}}},
+		},
+		"PHOTO": []*Property{{Name: "PHOTO", Value: [][]string{{"data:image/jpeg"}, {"base64", "MIICajCCAdOgAwIBAgICBEUwDQYJKoZIhv"}}}},
+		"ADR": []*Property{{Name: "ADR",
+			Value:  [][]string{{""}, {""}, {"1 Trafalgar Square"}, {"London"}, {""}, {"WC2N"}, {"United Kingdom"}},
+			Params: map[string][]string{"TYPE": {"home"}, "LABEL": {"1 Trafalgar Square\nLondon"}},
+		}},
+	}
+
+	for _, version := range []string{"3.0", "2.1"} {
+		var b bytes.Buffer
+		if err := NewEncoderWithOptions(&b, EncodeOptions{Version: version}).Encode(card); err != nil {
+			t.Fatalf("Expected no error when encoding vCard %s, got: %v", version, err)
+		}
+
+		decoded, err := NewDecoder(&b).Decode()
+		if err != nil {
+			t.Fatalf("Expected no error when decoding vCard %s, got: %v", version, err)
+		}
+		if v := decoded.Get(PropVersion).GetValueFirstText(); v != version {
+			t.Errorf("Expected VERSION to be %q but got %q", version, v)
+		}
+		if v := decoded.Get(PropFN).GetValueFirstText(); v != "Jörg Müller" {
+			t.Errorf("Expected FN to be %q in vCard %s but got %q", "Jörg Müller", version, v)
+		}
+		if pref := decoded.Pref(PropEmail); pref.GetValueFirstText() != "joerg@example.com" || !pref.IsHasType("pref") {
+			t.Errorf("Expected preferred EMAIL to have TYPE=PREF in vCard %s but got %+v", version, pref)
+		}
+		if _, ok := decoded.Pref(PropEmail).Params[ParamPref]; ok {
+			t.Errorf("Expected no PREF param in vCard %s", version)
+		}
+		photo := decoded.Get(PropPhoto)
+		if photo.GetValueFirstText() != "MIICajCCAdOgAwIBAgICBEUwDQYJKoZIhv" || !photo.IsHasType("JPEG") {
+			t.Errorf("Expected inline base64 PHOTO in vCard %s but got %+v", version, photo)
+		}
+		if label := decoded.Get("LABEL"); label == nil || label.GetValueFirstText() != "1 Trafalgar Square\nLondon" {
+			t.Errorf("Expected LABEL property in vCard %s but got %+v", version, label)
+		}
+	}
+
+	if card["EMAIL"][1].Params["PREF"] == nil {
+		t.Error("Expected encoding not to modify the card")
+	}
+}
+
+func TestEncoder_paramValue(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"ADR": []*Property{{Name: "ADR",
+			Value:  [][]string{{""}, {""}, {"123 Main St"}, {"Springfield"}, {""}, {""}, {""}},
+			Params: map[string][]string{"LABEL": {"123 Main St;Apt 4\n\"Springfield\""}},
+		}},
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	expected := "ADR;LABEL=\"123 Main St;Apt 4^n^'Springfield^'\":;;123 Main St;Springfield;;;\r\n"
+	if !strings.Contains(b.String(), expected) {
+		t.Errorf("Expected vcard to contain %q but got %q", expected, b.String())
+	}
+
+	decoded, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding card, got:", err)
+	}
+	if !reflect.DeepEqual(decoded[PropAdr][0].Params, card[PropAdr][0].Params) {
+		t.Errorf("Expected params %q but got %q", card[PropAdr][0].Params, decoded[PropAdr][0].Params)
+	}
+}
+
+func TestEncoder_folding(t *testing.T) {
+	note := strings.Repeat("Zoë ", 40)
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"NOTE":    []*Property{{Name: "NOTE", Value: [][]string{{note}}}},
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) < 4 {
+		t.Fatalf("Expected NOTE to be folded but got %q", b.String())
+	}
+	for i, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("Expected line %d to be at most 75 octets but got %d", i, len(l))
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("Expected line %d not to split a UTF-8 sequence but got %q", i, l)
+		}
+		if strings.Contains(l, "\n") {
+			t.Errorf("Expected no bare LF in line %d", i)
+		}
+	}
+
+	decoded, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding folded card, got:", err)
+	}
+	if v := decoded.Get(PropNote).GetValueFirstText(); v != note {
+		t.Errorf("Expected unfolded NOTE to be %q but got %q", note, v)
+	}
+
+	b.Reset()
+	if err := NewEncoderWithOptions(&b, EncodeOptions{DisableFolding: true}).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	if expected := "NOTE:" + note + "\r\n"; !strings.Contains(b.String(), expected) {
+		t.Errorf("Expected unfolded NOTE line but got %q", b.String())
+	}
+}
+
+func TestEncoder_paramOrder(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"EMAIL": []*Property{{Name: "EMAIL",
+			Value:  [][]string{{"me@joebloggs.com"}},
+			Params: map[string][]string{"PID": {"1.1"}, "PREF": {"1"}, "ALTID": {"1"}, "TYPE": {"home"}, "LANGUAGE": {"en"}},
+		}},
+	}
+
+	expected := "EMAIL;TYPE=home;PREF=1;ALTID=1;LANGUAGE=en;PID=1.1:me@joebloggs.com\r\n"
+	for i := 0; i < 10; i++ {
+		var b bytes.Buffer
+		if err := NewEncoder(&b).Encode(card); err != nil {
+			t.Fatal("Expected no error when encoding card, got:", err)
+		}
+		if !strings.Contains(b.String(), expected) {
+			t.Fatalf("Expected vcard to contain %q but got %q", expected, b.String())
+		}
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoderWithOptions(&b, EncodeOptions{ParamOrder: []string{ParamPid}}).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	expected = "EMAIL;PID=1.1;ALTID=1;LANGUAGE=en;PREF=1;TYPE=home:me@joebloggs.com\r\n"
+	if !strings.Contains(b.String(), expected) {
+		t.Errorf("Expected vcard to contain %q but got %q", expected, b.String())
+	}
+}
+
+func TestCard_CanonicalBytes(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"email": []*Property{{Name: "email",
+			Value:  [][]string{{"me@joebloggs.com"}},
+			Params: map[string][]string{"type": {"home"}, "Pref": {"1"}},
+		}},
+	}
+	other := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"email": []*Property{{Name: "EMAIL",
+			Value:  [][]string{{"me@joebloggs.com"}},
+			Params: map[string][]string{"PREF": {"1"}, "TYPE": {"home"}},
+		}},
+	}
+
+	b, err := card.CanonicalBytes()
+	if err != nil {
+		t.Fatal("Expected no error when encoding canonical card, got:", err)
+	}
+	expected := "BEGIN:VCARD\r\nVERSION:4.0\r\nEMAIL;TYPE=home;PREF=1:me@joebloggs.com\r\nEND:VCARD\r\n"
+	if string(b) != expected {
+		t.Errorf("Expected canonical card to be %q but got %q", expected, string(b))
+	}
+
+	h1, err := card.CanonicalHash()
+	if err != nil {
+		t.Fatal("Expected no error when hashing card, got:", err)
+	}
+	h2, _ := other.CanonicalHash()
+	if h1 != h2 {
+		t.Errorf("Expected equal hashes but got %s and %s", h1, h2)
+	}
+}
+
+var testCardAppleText = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"PRODID:-//Apple Inc.//Mac OS X 10.12.6//EN\r\n" +
+	"N:Bloggs;Joe;;;\r\n" +
+	"FN:Joe Bloggs\r\n" +
+	"item1.EMAIL;type=INTERNET;type=pref:me@joebloggs.com\r\n" +
+	"item1.X-ABLabel:_$!<Other>!$_\r\n" +
+	"TEL;type=CELL;type=VOICE;type=pref:+44 20 1234 5678\r\n" +
+	"item2.ADR;type=HOME;type=pref:;;1 Trafalgar Square;London;;WC2N;United King\r\n" +
+	" dom\r\n" +
+	"item2.X-ABADR:gb\r\n" +
+	"NOTE:C:\\\\Users\\\\joe\\, with a note\r\n" +
+	"X-ABUID:5AD380FD-B2DE-4261-BA99-DE1D1DB52FBE:ABPerson\r\n" +
+	"END:VCARD\r\n"
+
+func TestEncoder_foldingInvalidUTF8(t *testing.T) {
+	note := strings.Repeat("\xa0", 100)
+	dec := NewDecoder(strings.NewReader("BEGIN:VCARD\r\nVERSION:4.0\r\nNOTE:" + note + "\r\nEND:VCARD\r\n"))
+	dec.Lossless = true
+	card, err := dec.Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding card, got:", err)
+	}
+
+	var b bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- NewEncoder(&b).Encode(card)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal("Expected no error when encoding card, got:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected encoding of a non UTF-8 value to terminate")
+	}
+	for i, l := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("Expected line %d to be at most 75 octets but got %d", i, len(l))
+		}
+	}
+	decoded, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding folded card, got:", err)
+	}
+	if v := decoded.Get(PropNote).GetValueFirstText(); v != note {
+		t.Errorf("Expected unfolded NOTE to be %q but got %q", note, v)
+	}
+}
+
+func TestEncoder_lossless(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(testCardAppleText))
+	dec.Lossless = true
+	card, err := dec.Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding card, got:", err)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	if b.String() != testCardAppleText {
+		t.Errorf("Expected untouched card to round-trip, expected\n%q\n but got \n%q", testCardAppleText, b.String())
+	}
+
+	card.Get(PropFN).Value = [][]string{{"Joseph Bloggs"}}
+	card.SetValue(PropNickName, [][]string{{"Joe"}})
+	b.Reset()
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	expected := strings.Replace(testCardAppleText, "FN:Joe Bloggs", "FN:Joseph Bloggs", 1)
+	expected = strings.Replace(expected, "END:VCARD", "NICKNAME:Joe\r\nEND:VCARD", 1)
+	if b.String() != expected {
+		t.Errorf("Expected changed card to keep its order, expected\n%q\n but got \n%q", expected, b.String())
+	}
+}
+
+type errWriter struct {
+	writes int
+	err    error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, w.err
+}
+
+func TestEncoder_writeError(t *testing.T) {
+	w := &errWriter{err: errors.New("disk full")}
+	if err := NewEncoder(w).Encode(testCard); err != w.err {
+		t.Errorf("Expected write error %v but got %v", w.err, err)
+	}
+	if w.writes != 1 {
+		t.Errorf("Expected card to be written with a single write but got %d", w.writes)
+	}
+	if err := NewEncoder(w).WriteProperty(testCard.Get(PropFN)); err != w.err {
+		t.Errorf("Expected write error %v from WriteProperty but got %v", w.err, err)
+	}
+}
+
+func TestEncoder_EncodeAll(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewEncoder(&b).EncodeAll([]Card{testCard, testCard}); err != nil {
+		t.Fatal("Expected no error when encoding cards, got:", err)
+	}
+	dec := NewDecoder(&b)
+	for i := 0; i < 2; i++ {
+		card, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Expected no error when decoding card %d, got: %v", i, err)
+		}
+		if !reflect.DeepEqual(card, testCard) {
+			t.Errorf("Invalid parsed card %d: expected %+v but got %+v", i, testCard, card)
+		}
+	}
+
+	b.Reset()
+	invalid := Card{"FN": []*Property{{Name: "FN", Value: [][]string{{"Joe Bloggs"}}}}}
+	if err := NewEncoder(&b).EncodeAll([]Card{testCard, invalid}); err == nil {
+		t.Error("Expected an error when encoding a card without VERSION")
+	}
+	if b.Len() != 0 {
+		t.Errorf("Expected nothing to be written but got %q", b.String())
+	}
+}
+
+func TestEncoder_maxBytes(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		PropTel:     {{Name: PropTel, Value: [][]string{{"+1 555 0100"}}}},
+		PropEmail: {
+			{Name: PropEmail, Params: map[string][]string{ParamPref: {"1"}}, Value: [][]string{{"joe@example.com"}}},
+			{Name: PropEmail, Value: [][]string{{"joe@home.example.com"}}},
+		},
+		PropNote:  {{Name: PropNote, Value: [][]string{{strings.Repeat("note ", 20)}}}},
+		PropPhoto: {{Name: PropPhoto, Value: [][]string{{"http://example.com/joe.jpg"}}}},
+		"X-FOO":   {{Name: "X-FOO", Value: [][]string{{"bar"}}}},
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoderWithOptions(&b, EncodeOptions{MaxBytes: 110}).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding within a budget, got:", err)
+	}
+	out := b.String()
+	if len(out) > 110 {
+		t.Errorf("Expected at most 110 bytes but got %d:\n%s", len(out), out)
+	}
+	for _, s := range []string{"FN:Joe Bloggs", "TEL:+1 555 0100", "EMAIL;PREF=1:joe@example.com"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected compact card to keep %q but got:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{"X-FOO", "PHOTO", "NOTE", "joe@home.example.com"} {
+		if strings.Contains(out, s) {
+			t.Errorf("Expected compact card to drop %q but got:\n%s", s, out)
+		}
+	}
+	if len(card[PropEmail]) != 2 || card[PropNote] == nil {
+		t.Error("Expected the card not to be modified")
+	}
+
+	b.Reset()
+	if err := NewEncoderWithOptions(&b, EncodeOptions{MaxBytes: 20}).Encode(card); err == nil {
+		t.Error("Expected an error when the required properties do not fit")
+	} else if b.Len() != 0 {
+		t.Errorf("Expected nothing written on error but got %q", b.String())
+	}
+}
+
+func TestEncoder_quotedPrintableLines(t *testing.T) {
+	note := strings.Repeat("Grüße aus München, ", 20)
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"FN":      []*Property{{Name: "FN", Value: [][]string{{"Jörg Müller"}}}},
+		"NOTE":    []*Property{{Name: "NOTE", Value: [][]string{{note}}, Params: map[string][]string{"LANGUAGE": {"de"}}}},
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoderWithOptions(&b, EncodeOptions{Version: "2.1"}).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding vCard 2.1, got:", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("Expected lines of at most 76 octets but got %d octets: %q", len(line), line)
+		}
+	}
+
+	decoded, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding vCard 2.1, got:", err)
+	}
+	if v := decoded.Get(PropNote).GetValueFirstText(); v != note {
+		t.Errorf("Expected NOTE %q but got %q", note, v)
+	}
+}
@@ -0,0 +1,270 @@
+package go_vcard
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+a position in WGS-84,Uncertainty is in meters and 0 if unknown
+ */
+type Geo struct {
+	Latitude float64
+	Longitude float64
+	Altitude float64
+	HasAltitude bool
+	Uncertainty float64
+}
+
+/*
+parse a geo: URI(RFC 5870),e.g. geo:37.386013,-122.082932;u=10,or the
+vCard 3.0 form lat;lon,e.g. 37.386013;-122.082932
+ */
+func ParseGeo(s string) (*Geo,error) {
+	s = strings.TrimSpace(s)
+	var coords []string
+	var params []string
+	if len(s) >= 4 && strings.EqualFold(s[:4],"geo:"){
+		parts := strings.Split(s[4:],";")
+		coords = strings.Split(parts[0],",")
+		params = parts[1:]
+	}else{
+		coords = strings.Split(s,";")
+		if len(coords) != 2{
+			coords = strings.Split(s,",")
+		}
+		if len(coords) != 2{
+			return nil,fmt.Errorf("vcard:invalid geo %q",s)
+		}
+	}
+	if len(coords) < 2 || len(coords) > 3{
+		return nil,fmt.Errorf("vcard:invalid geo URI %q",s)
+	}
+	var vals [3]float64
+	for i,c := range coords{
+		v,err := strconv.ParseFloat(strings.TrimSpace(c),64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v,0){
+			return nil,fmt.Errorf("vcard:invalid coordinate %q in geo %q",c,s)
+		}
+		vals[i] = v
+	}
+	g := &Geo{Latitude:vals[0],Longitude:vals[1],Altitude:vals[2],HasAltitude:len(coords) == 3}
+	if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180{
+		return nil,fmt.Errorf("vcard:geo %q out of range",s)
+	}
+	for _,param := range params{
+		kv := strings.SplitN(param,"=",2)
+		if len(kv) != 2{
+			continue
+		}
+		switch strings.ToLower(kv[0]) {
+		case "crs":
+			if !strings.EqualFold(kv[1],"wgs84"){
+				return nil,fmt.Errorf("vcard:unsupported crs %q in geo %q",kv[1],s)
+			}
+		case "u":
+			u,err := strconv.ParseFloat(kv[1],64)
+			if err != nil || u < 0{
+				return nil,fmt.Errorf("vcard:invalid uncertainty %q in geo %q",kv[1],s)
+			}
+			g.Uncertainty = u
+		}
+	}
+	return g,nil
+}
+
+/*
+the geo: URI,e.g. geo:37.386013,-122.082932;u=10
+ */
+func (g *Geo) URI() string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v,'f',-1,64)
+	}
+	uri := "geo:"+f(g.Latitude)+","+f(g.Longitude)
+	if g.HasAltitude{
+		uri += ","+f(g.Altitude)
+	}
+	if g.Uncertainty > 0{
+		uri += ";u="+f(g.Uncertainty)
+	}
+	return uri
+}
+
+/*
+return the GEO of the card,nil if it has no GEO
+ */
+func (c Card) Geo() (*Geo,error) {
+	p := c.Get(PropGEO)
+	if p == nil{
+		return nil,nil
+	}
+	return ParseGeo(joinValue(p.Value))
+}
+
+func (c Card) SetGeo(g *Geo)  {
+	c.Set(PropGEO,&Property{Name:PropGEO,Value:parseValues(g.URI())})
+}
+
+/*
+resolve a TZ value to a location.typ is the VALUE param,"text" for an IANA
+name,e.g. America/New_York,"utc-offset" for -0500 or -05:00,"uri" for a URI
+ending with an IANA name.an empty typ accept all of them
+ */
+func ParseTimeZone(s,typ string) (*time.Location,error) {
+	s = strings.TrimSpace(s)
+	if s == ""{
+		return nil,fmt.Errorf("vcard:empty time zone")
+	}
+	switch strings.ToLower(typ) {
+	case "utc-offset":
+		return parseUTCOffset(s)
+	case "uri":
+		return uriTimeZone(s)
+	case "text":
+		//vCard 3.0 TZ is often an offset without VALUE=utc-offset
+		if loc,err := parseUTCOffset(s);err == nil{
+			return loc,nil
+		}
+		return loadTimeZone(s)
+	case "":
+		if loc,err := parseUTCOffset(s);err == nil{
+			return loc,nil
+		}
+		if strings.Contains(s,":"){
+			return uriTimeZone(s)
+		}
+		return loadTimeZone(s)
+	}
+	return nil,fmt.Errorf("vcard:unsupported time zone value type %q",typ)
+}
+
+func loadTimeZone(name string) (*time.Location,error) {
+	loc,err := time.LoadLocation(name)
+	if err != nil || name == "Local"{
+		return nil,fmt.Errorf("vcard:unknown time zone %q",name)
+	}
+	return loc,nil
+}
+
+/*
+e.g. -0500,+05:30,-05,Z
+ */
+func parseUTCOffset(s string) (*time.Location,error) {
+	if s == "Z" || s == "z"{
+		return time.UTC,nil
+	}
+	h := strings.Replace(s[1:],":","",1)
+	if (s[0] != '+' && s[0] != '-') || (len(h) != 2 && len(h) != 4) || !isDigits(h){
+		return nil,fmt.Errorf("vcard:invalid utc-offset %q",s)
+	}
+	offset := atoi(h[:2])*3600
+	if len(h) == 4{
+		offset += atoi(h[2:])*60
+	}
+	if offset >= 24*3600{
+		return nil,fmt.Errorf("vcard:utc-offset %q out of range",s)
+	}
+	if s[0] == '-'{
+		offset = -offset
+	}
+	if offset == 0{
+		return time.UTC,nil
+	}
+	return time.FixedZone(formatUTCOffset(offset),offset),nil
+}
+
+/*
+find an IANA name at the end of the path of u,e.g.
+https://example.com/tz-database/America/Montreal
+ */
+func uriTimeZone(s string) (*time.Location,error) {
+	u,err := url.Parse(s)
+	if err != nil || u.Scheme == ""{
+		return nil,fmt.Errorf("vcard:invalid time zone URI %q",s)
+	}
+	path := u.Path
+	if path == ""{
+		path = u.Opaque
+	}
+	segs := strings.FieldsFunc(path,func(r rune) bool {
+		return r == '/' || r == ':'
+	})
+	//the longest IANA names have 3 segments,e.g. America/Argentina/Buenos_Aires
+	for n := 3;n > 0;n--{
+		if n > len(segs){
+			continue
+		}
+		if loc,err := loadTimeZone(strings.Join(segs[len(segs)-n:],"/"));err == nil{
+			return loc,nil
+		}
+	}
+	return nil,fmt.Errorf("vcard:no time zone found in URI %q",s)
+}
+
+/*
+return the TZ of the card as a location,nil if it has no TZ
+ */
+func (c Card) TimeZone() (*time.Location,error) {
+	p := c.Get(PropTZ)
+	if p == nil{
+		return nil,nil
+	}
+	_,typ := findParam(p.Params,ParamValue)
+	return ParseTimeZone(joinValue(p.Value),typ)
+}
+
+/*
+set TZ to the IANA name of loc,or to the utc-offset of a fixed zone
+ */
+func (c Card) SetTimeZone(loc *time.Location)  {
+	p := &Property{Name:PropTZ}
+	if _,err := loadTimeZone(loc.String());err == nil && loc.String() != ""{
+		p.Value = [][]string{{loc.String()}}
+	}else{
+		_,offset := time.Now().In(loc).Zone()
+		p.Value = [][]string{{formatUTCOffset(offset)}}
+		p.SetParam(ParamValue,"utc-offset")
+	}
+	c.Set(PropTZ,p)
+}
+
+func formatUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0{
+		sign,offset = "-",-offset
+	}
+	return fmt.Sprintf("%s%02d%02d",sign,offset/3600,offset%3600/60)
+}
+
+/*
+the GEO param of the address,nil if it has no GEO param
+ */
+func (a *Address) Geo() (*Geo,error) {
+	if a.Property == nil{
+		return nil,nil
+	}
+	k,_ := findParam(a.Params,ParamGEO)
+	if k == ""{
+		return nil,nil
+	}
+	//an unquoted geo URI is split on ','
+	return ParseGeo(strings.Join(a.Params[k],","))
+}
+
+/*
+the TZ param of the address,nil if it has no TZ param
+ */
+func (a *Address) TimeZone() (*time.Location,error) {
+	if a.Property == nil{
+		return nil,nil
+	}
+	k,v := findParam(a.Params,ParamTZ)
+	if k == ""{
+		return nil,nil
+	}
+	return ParseTimeZone(v,"")
+}
@@ -0,0 +1,124 @@
+package go_vcard
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGeo(t *testing.T) {
+	tests := []struct {
+		s   string
+		geo Geo
+		uri string
+	}{
+		{"geo:37.386013,-122.082932", Geo{Latitude: 37.386013, Longitude: -122.082932}, "geo:37.386013,-122.082932"},
+		{"GEO:48.2010,16.3695,183;crs=wgs84;u=40", Geo{Latitude: 48.201, Longitude: 16.3695, Altitude: 183, HasAltitude: true, Uncertainty: 40}, "geo:48.201,16.3695,183;u=40"},
+		{"37.386013;-122.082932", Geo{Latitude: 37.386013, Longitude: -122.082932}, "geo:37.386013,-122.082932"},
+	}
+	for _, test := range tests {
+		g, err := ParseGeo(test.s)
+		if err != nil {
+			t.Errorf("ParseGeo(%q): unexpected error: %v", test.s, err)
+			continue
+		}
+		if *g != test.geo {
+			t.Errorf("ParseGeo(%q) = %+v, want %+v", test.s, *g, test.geo)
+		}
+		if uri := g.URI(); uri != test.uri {
+			t.Errorf("ParseGeo(%q).URI() = %q, want %q", test.s, uri, test.uri)
+		}
+	}
+
+	for _, s := range []string{"", "geo:37.3", "geo:91,0", "geo:1,2;u=-1", "geo:1,2;crs=nad27", "here"} {
+		if _, err := ParseGeo(s); err == nil {
+			t.Errorf("ParseGeo(%q): expected an error", s)
+		}
+	}
+}
+
+func TestCard_TimeZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	at := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value, typ string
+		offset     int
+	}{
+		{"America/New_York", "", -5 * 3600},
+		{"-0500", "utc-offset", -5 * 3600},
+		{"+05:30", "", 5*3600 + 30*60},
+		{"-05:00", "text", -5 * 3600},
+		{"https://example.com/tz-database/America/Argentina/Buenos_Aires", "uri", -3 * 3600},
+		{"Z", "utc-offset", 0},
+	}
+	for _, test := range tests {
+		card := Card{PropTZ: {{Name: PropTZ, Value: [][]string{{test.value}}}}}
+		if test.typ != "" {
+			card[PropTZ][0].SetParam(ParamValue, test.typ)
+		}
+		loc, err := card.TimeZone()
+		if err != nil {
+			t.Errorf("TZ %q: unexpected error: %v", test.value, err)
+			continue
+		}
+		if _, offset := at.In(loc).Zone(); offset != test.offset {
+			t.Errorf("TZ %q: expected offset %d but got %d", test.value, test.offset, offset)
+		}
+	}
+
+	for _, test := range [][2]string{{"Mars/Olympus", ""}, {"-0500", "text/plain"}, {"+2500", "utc-offset"}, {"urn:x", "uri"}} {
+		card := Card{PropTZ: {{Name: PropTZ, Value: [][]string{{test[0]}}}}}
+		if test[1] != "" {
+			card[PropTZ][0].SetParam(ParamValue, test[1])
+		}
+		if _, err := card.TimeZone(); err == nil {
+			t.Errorf("TZ %q: expected an error", test[0])
+		}
+	}
+
+	card := make(Card)
+	card.SetTimeZone(newYork)
+	if v := card.Get(PropTZ).GetValueFirstText(); v != "America/New_York" {
+		t.Errorf("Expected TZ America/New_York but got %q", v)
+	}
+	card.SetTimeZone(time.FixedZone("", -4*3600))
+	if p := card.Get(PropTZ); p.GetValueFirstText() != "-0400" || p.GetFirstParamVal(ParamValue) != "utc-offset" {
+		t.Errorf("Expected TZ;VALUE=utc-offset:-0400 but got %+v", p)
+	}
+}
+
+func TestAddress_GeoAndTimeZone(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"ADR;GEO=\"geo:12.3457,78.910\";TZ=America/New_York:;;123 Main Street;Any Town;CA;91921-1234;U.S.A.\r\n" +
+		"GEO:geo:37.386013,-122.082932\r\n" +
+		"END:VCARD\r\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adr := card.Address()
+	if g, err := adr.Geo(); err != nil || g.Latitude != 12.3457 || g.Longitude != 78.91 {
+		t.Errorf("Unexpected ADR geo %+v, %v", g, err)
+	}
+	if loc, err := adr.TimeZone(); err != nil {
+		t.Skip("no time zone database:", err)
+	} else if loc.String() != "America/New_York" {
+		t.Errorf("Expected ADR time zone America/New_York but got %v", loc)
+	}
+	if g, err := card.Geo(); err != nil || g.Latitude != 37.386013 || g.Longitude != -122.082932 {
+		t.Errorf("Unexpected card geo %+v, %v", g, err)
+	}
+
+	card.SetGeo(&Geo{Latitude: 1.5, Longitude: 2, Uncertainty: 10})
+	if g, err := card.Geo(); err != nil || g.URI() != "geo:1.5,2;u=10" {
+		t.Errorf("Unexpected geo after SetGeo %+v, %v", g, err)
+	}
+	if g, err := (&Address{}).Geo(); g != nil || err != nil {
+		t.Errorf("Expected no geo for an address without property, got %+v, %v", g, err)
+	}
+}
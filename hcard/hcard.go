@@ -0,0 +1,715 @@
+//http://microformats.org/wiki/h-card
+//http://microformats.org/wiki/hcard
+//h-card (microformats2) and legacy hCard extraction from HTML,and h-card rendering
+package hcard
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	vcard "github.com/ScottAI/go-vcard"
+)
+
+/*
+an HTML element or,when tag is "",a text
+ */
+type node struct {
+	tag string
+	attrs map[string]string
+	classes []string
+	children []*node
+	text string
+}
+
+func (n *node) hasClass(class string) bool {
+	for _,c := range n.classes{
+		if c == class{
+			return true
+		}
+	}
+	return false
+}
+
+/*
+parse HTML leniently:unclosed and void elements are closed automatically
+and HTML entities are known.the content of script and style is dropped,
+other markup that is not well-formed enough is an error
+ */
+func parseHTML(r io.Reader) (*node,error) {
+	src,err := ioutil.ReadAll(r)
+	if err != nil{
+		return nil,err
+	}
+	d := xml.NewDecoder(bytes.NewReader(stripRawText(src)))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	doc := &node{tag:"#document"}
+	stack := []*node{doc}
+	for{
+		tok,err := d.Token()
+		if err == io.EOF{
+			return doc,nil
+		}
+		if err != nil{
+			return nil,fmt.Errorf("hcard:%v",err)
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{tag:strings.ToLower(t.Name.Local),attrs:make(map[string]string)}
+			for _,a := range t.Attr{
+				n.attrs[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			n.classes = strings.Fields(n.attrs["class"])
+			top.children = append(top.children,n)
+			stack = append(stack,n)
+		case xml.EndElement:
+			tag := strings.ToLower(t.Name.Local)
+			for i := len(stack)-1;i > 0;i--{
+				if stack[i].tag == tag{
+					stack = stack[:i]
+					break
+				}
+			}
+		case xml.CharData:
+			top.children = append(top.children,&node{text:string(t)})
+		}
+	}
+}
+
+//elements whose content is raw text and not markup
+var rawTextTags = []string{"script","style"}
+
+/*
+drop the content of the raw text elements,e.g. the '<' of a script is not
+the start of a tag
+ */
+func stripRawText(src []byte) []byte {
+	lower := make([]byte,len(src))
+	for i,c := range src{
+		if 'A' <= c && c <= 'Z'{
+			c += 'a'-'A'
+		}
+		lower[i] = c
+	}
+	var out []byte
+	last := 0
+	for i := 0;i < len(lower);{
+		j := bytes.IndexByte(lower[i:],'<')
+		if j < 0{
+			break
+		}
+		i += j+1
+		for _,tag := range rawTextTags{
+			if !bytes.HasPrefix(lower[i:],[]byte(tag)) || !isTagEnd(lower,i+len(tag)){
+				continue
+			}
+			start := bytes.IndexByte(lower[i:],'>')
+			if start < 0{
+				break
+			}
+			start += i+1
+			end := len(lower)
+			if k := bytes.Index(lower[start:],[]byte("</"+tag));k >= 0{
+				end = start+k
+			}
+			out = append(out,src[last:start]...)
+			last,i = end,end
+			break
+		}
+	}
+	if out == nil{
+		return src
+	}
+	return append(out,src[last:]...)
+}
+
+func isTagEnd(b []byte,i int) bool {
+	if i >= len(b){
+		return false
+	}
+	switch b[i] {
+	case '>','/',' ','\t','\r','\n','\f':
+		return true
+	}
+	return false
+}
+
+/*
+legacy hCard class to microformats2 property class
+ */
+var legacyClasses = map[string]string{
+	"fn":"p-name","n":"p-n","family-name":"p-family-name","given-name":"p-given-name",
+	"additional-name":"p-additional-name","honorific-prefix":"p-honorific-prefix",
+	"honorific-suffix":"p-honorific-suffix","nickname":"p-nickname",
+	"org":"p-org","organization-name":"p-organization-name","organization-unit":"p-organization-unit",
+	"title":"p-job-title","role":"p-role","email":"u-email","tel":"p-tel",
+	"url":"u-url","photo":"u-photo","logo":"u-logo","uid":"u-uid",
+	"adr":"p-adr","post-office-box":"p-post-office-box","extended-address":"p-extended-address",
+	"street-address":"p-street-address","locality":"p-locality","region":"p-region",
+	"postal-code":"p-postal-code","country-name":"p-country-name","label":"p-label",
+	"geo":"p-geo","latitude":"p-latitude","longitude":"p-longitude",
+	"note":"p-note","bday":"dt-bday","category":"p-category",
+}
+
+//legacy properties that hold sub-properties
+var legacyContainers = map[string]bool{"n":true,"adr":true,"geo":true,"org":true}
+
+/*
+a property of an item
+ */
+type property struct {
+	name string //without prefix,e.g. street-address
+	prefix string //p,u,dt or e
+	node *node
+	children []property //sub-properties of a nested item,nil if not nested
+}
+
+type parser struct {
+	base *url.URL
+	legacy bool
+}
+
+/*
+extract the cards marked up with h-card,or with the legacy vcard class,
+from HTML.relative URLs are resolved against baseURL when it is not empty
+ */
+func Parse(r io.Reader,baseURL string) ([]vcard.Card,error) {
+	doc,err := parseHTML(r)
+	if err != nil{
+		return nil,err
+	}
+	var base *url.URL
+	if baseURL != ""{
+		if base,err = url.Parse(baseURL);err != nil{
+			return nil,fmt.Errorf("hcard:base URL:%v",err)
+		}
+	}
+	var cards []vcard.Card
+	var find func(n *node)
+	find = func(n *node) {
+		for _,c := range n.children{
+			switch {
+			case c.hasClass("h-card"):
+				cards = append(cards,(&parser{base:base}).card(c))
+			case c.hasClass("vcard"):
+				cards = append(cards,(&parser{base:base,legacy:true}).card(c))
+			default:
+				find(c)
+			}
+		}
+	}
+	find(doc)
+	return cards,nil
+}
+
+/*
+property classes of an element
+ */
+func (ps *parser) propClasses(n *node) [][2]string {
+	var props [][2]string
+	for _,c := range n.classes{
+		if ps.legacy{
+			if c = legacyClasses[c];c == ""{
+				continue
+			}
+		}
+		if i := strings.IndexByte(c,'-');i > 0{
+			switch c[:i] {
+			case "p","u","dt","e":
+				props = append(props,[2]string{c[:i],c[i+1:]})
+			}
+		}
+	}
+	return props
+}
+
+/*
+the element is a nested item,its descendants are its own properties
+ */
+func (ps *parser) isItem(n *node) bool {
+	if ps.legacy{
+		for _,c := range n.classes{
+			if legacyContainers[c] || c == "vcard"{
+				return true
+			}
+		}
+		return false
+	}
+	for _,c := range n.classes{
+		if strings.HasPrefix(c,"h-"){
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *parser) properties(n *node) []property {
+	var props []property
+	for _,c := range n.children{
+		if c.tag == ""{
+			continue
+		}
+		classes := ps.propClasses(c)
+		if ps.isItem(c){
+			sub := ps.properties(c)
+			if sub == nil{
+				sub = []property{}
+			}
+			for _,pc := range classes{
+				props = append(props,property{name:pc[1],prefix:pc[0],node:c,children:sub})
+			}
+			continue
+		}
+		for _,pc := range classes{
+			props = append(props,property{name:pc[1],prefix:pc[0],node:c})
+		}
+		props = append(props,ps.properties(c)...)
+	}
+	return props
+}
+
+/*
+value of a property by the parsing rules of its prefix
+ */
+func (ps *parser) value(p property) string {
+	n := p.node
+	switch p.prefix {
+	case "u":
+		for _,attr := range []string{"href","src","poster","data"}{
+			if v,ok := n.attrs[attr];ok && (attr != "href" || n.tag == "a" || n.tag == "area" || n.tag == "link"){
+				return ps.resolve(v)
+			}
+		}
+	case "dt":
+		if v,ok := valueClass(n);ok{
+			return v
+		}
+		switch n.tag {
+		case "time","ins","del":
+			if v,ok := n.attrs["datetime"];ok{
+				return v
+			}
+		}
+	case "e":
+		return textContent(n)
+	}
+	if v,ok := valueClass(n);ok{
+		return v
+	}
+	switch n.tag {
+	case "abbr","link":
+		if v,ok := n.attrs["title"];ok{
+			return v
+		}
+	case "data","input":
+		if v,ok := n.attrs["value"];ok{
+			return v
+		}
+	case "img","area":
+		if v,ok := n.attrs["alt"];ok{
+			return v
+		}
+	}
+	return textContent(n)
+}
+
+func (ps *parser) resolve(v string) string {
+	if ps.base == nil{
+		return v
+	}
+	u,err := url.Parse(v)
+	if err != nil{
+		return v
+	}
+	return ps.base.ResolveReference(u).String()
+}
+
+/*
+the value class pattern:the concatenated values of the descendants with
+the class value,or value-title
+ */
+func valueClass(n *node) (string,bool) {
+	var vals []string
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _,c := range n.children{
+			switch {
+			case c.hasClass("value-title"):
+				vals = append(vals,c.attrs["title"])
+			case c.hasClass("value"):
+				switch c.tag {
+				case "img","area":
+					vals = append(vals,c.attrs["alt"])
+				case "data","input":
+					vals = append(vals,c.attrs["value"])
+				case "abbr":
+					if v,ok := c.attrs["title"];ok{
+						vals = append(vals,v)
+						continue
+					}
+					vals = append(vals,textContent(c))
+				default:
+					vals = append(vals,textContent(c))
+				}
+			case c.tag != "":
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return strings.Join(vals,""),len(vals) > 0
+}
+
+/*
+text of the element with the white space collapsed
+ */
+func textContent(n *node) string {
+	var b strings.Builder
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _,c := range n.children{
+			if c.tag == ""{
+				b.WriteString(c.text)
+				continue
+			}
+			if c.tag == "script" || c.tag == "style"{
+				continue
+			}
+			if c.tag == "img"{
+				b.WriteString(c.attrs["alt"])
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String())," ")
+}
+
+/*
+types of a legacy tel,email or adr,e.g. <span class="type">work</span>
+ */
+func types(n *node) []string {
+	var ts []string
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _,c := range n.children{
+			if c.tag == ""{
+				continue
+			}
+			if c.hasClass("type"){
+				if t := strings.ToLower(textContent(c));t != ""{
+					ts = append(ts,t)
+				}
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return ts
+}
+
+func (ps *parser) first(props []property,name string) string {
+	for _,p := range props{
+		if p.name == name{
+			return ps.value(p)
+		}
+	}
+	return ""
+}
+
+func (ps *parser) card(root *node) vcard.Card {
+	c := make(vcard.Card)
+	c.Set(vcard.PropVersion,&vcard.Property{Name:vcard.PropVersion,Value:[][]string{{"4.0"}}})
+	props := ps.properties(root)
+	add := func(name,value string,ts []string) {
+		if value == ""{
+			return
+		}
+		p := &vcard.Property{Name:name,Value:[][]string{{value}}}
+		for _,t := range ts{
+			p.AddParam(vcard.ParamType,t)
+		}
+		c.Add(name,p)
+	}
+
+	//the structured name is made of the properties of the card and of
+	//a legacy n
+	nameProps := props
+	var categories []string
+	for _,p := range props{
+		if p.name == "n"{
+			nameProps = append(nameProps,p.children...)
+		}
+	}
+	name := &vcard.Name{
+		FamilyName:ps.first(nameProps,"family-name"),
+		GivenName:ps.first(nameProps,"given-name"),
+		AdditionalName:ps.first(nameProps,"additional-name"),
+		HonorificPrefix:ps.first(nameProps,"honorific-prefix"),
+		HonorificSuffix:ps.first(nameProps,"honorific-suffix"),
+	}
+
+	for _,p := range props{
+		switch p.name {
+		case "name":
+			if c.Get(vcard.PropFN) == nil{
+				add(vcard.PropFN,ps.value(p),nil)
+			}
+		case "nickname":
+			add(vcard.PropNickName,ps.value(p),nil)
+		case "email":
+			v := strings.TrimPrefix(ps.value(p),"mailto:")
+			if i := strings.IndexByte(v,'?');i >= 0{
+				v = v[:i]
+			}
+			add(vcard.PropEmail,v,types(p.node))
+		case "tel":
+			add(vcard.PropTel,strings.TrimPrefix(ps.value(p),"tel:"),types(p.node))
+		case "url":
+			add(vcard.PropUrl,ps.value(p),nil)
+		case "photo":
+			add(vcard.PropPhoto,ps.value(p),nil)
+		case "logo":
+			add(vcard.PropLogo,ps.value(p),nil)
+		case "uid":
+			add(vcard.PropUid,ps.value(p),nil)
+		case "bday":
+			add(vcard.PropBday,basicDate(ps.value(p)),nil)
+		case "anniversary":
+			add(vcard.PropAnniversary,basicDate(ps.value(p)),nil)
+		case "note":
+			add(vcard.PropNote,ps.value(p),nil)
+		case "job-title":
+			add(vcard.PropTiTle,ps.value(p),nil)
+		case "role":
+			add(vcard.PropRole,ps.value(p),nil)
+		case "category":
+			if v := ps.value(p);v != ""{
+				categories = append(categories,v)
+			}
+		case "org":
+			if on := ps.first(p.children,"organization-name");on != ""{
+				value := [][]string{{on}}
+				if ou := ps.first(p.children,"organization-unit");ou != ""{
+					value = append(value,[]string{ou})
+				}
+				c.Add(vcard.PropOrg,&vcard.Property{Name:vcard.PropOrg,Value:value})
+				continue
+			}
+			if p.children != nil && ps.first(p.children,"name") != ""{
+				//e.g. p-org h-card
+				add(vcard.PropOrg,ps.first(p.children,"name"),nil)
+				continue
+			}
+			add(vcard.PropOrg,ps.value(p),nil)
+		case "adr":
+			if len(p.children) == 0{
+				//an address without structure
+				ps.addAddress(c,&vcard.Address{StreetAddress:ps.value(p)},"",types(p.node))
+				continue
+			}
+			ps.addAddress(c,ps.address(p.children),ps.first(p.children,"label"),types(p.node))
+		case "geo":
+			if len(p.children) > 0{
+				add(vcard.PropGEO,geoURI(ps.first(p.children,"latitude"),ps.first(p.children,"longitude")),nil)
+				continue
+			}
+			//legacy geo text is "latitude;longitude"
+			if parts := strings.Split(ps.value(p),";");len(parts) == 2{
+				add(vcard.PropGEO,geoURI(parts[0],parts[1]),nil)
+			}
+		}
+	}
+	//address properties directly on the card
+	if adr := ps.address(props);*adr != (vcard.Address{}){
+		ps.addAddress(c,adr,ps.first(props,"label"),nil)
+	}
+	if lat,long := ps.first(props,"latitude"),ps.first(props,"longitude");c.Get(vcard.PropGEO) == nil && lat != "" && long != ""{
+		add(vcard.PropGEO,geoURI(lat,long),nil)
+	}
+	if len(categories) > 0{
+		c.Add(vcard.PropCategories,&vcard.Property{Name:vcard.PropCategories,Value:[][]string{categories}})
+	}
+	if *name != (vcard.Name{}){
+		c.AddName(name)
+	}
+
+	if c.Get(vcard.PropFN) == nil{
+		//the implied name
+		fn := strings.Join(strings.Fields(strings.Join([]string{
+			name.HonorificPrefix,name.GivenName,name.AdditionalName,name.FamilyName,name.HonorificSuffix,
+		}," "))," ")
+		if fn == ""{
+			fn = textContent(root)
+		}
+		add(vcard.PropFN,fn,nil)
+	}
+	return c
+}
+
+func (ps *parser) address(props []property) *vcard.Address {
+	return &vcard.Address{
+		PostOfficeBox:ps.first(props,"post-office-box"),
+		ExtendedAddress:ps.first(props,"extended-address"),
+		StreetAddress:ps.first(props,"street-address"),
+		Locality:ps.first(props,"locality"),
+		Region:ps.first(props,"region"),
+		PostalCode:ps.first(props,"postal-code"),
+		Country:ps.first(props,"country-name"),
+	}
+}
+
+func (ps *parser) addAddress(c vcard.Card,adr *vcard.Address,label string,ts []string)  {
+	c.AddAdress(adr)
+	adr.Property.Name = vcard.PropAdr
+	for _,t := range ts{
+		adr.Property.AddParam(vcard.ParamType,t)
+	}
+	if label != ""{
+		adr.Property.SetParam("LABEL",label)
+	}
+}
+
+func geoURI(lat,long string) string {
+	return "geo:"+strings.TrimSpace(lat)+","+strings.TrimSpace(long)
+}
+
+/*
+YYYY-MM-DD to the basic form YYYYMMDD of vCard
+ */
+func basicDate(v string) string {
+	if len(v) == 10 && v[4] == '-' && v[7] == '-'{
+		return v[:4]+v[5:7]+v[8:]
+	}
+	return v
+}
+
+/*
+YYYYMMDD to the extended form YYYY-MM-DD of HTML
+ */
+func extendedDate(v string) string {
+	if len(v) == 8 && strings.Trim(v,"0123456789") == ""{
+		return v[:4]+"-"+v[4:6]+"-"+v[6:]
+	}
+	return v
+}
+
+/*
+render the card as h-card HTML
+ */
+func Render(w io.Writer,c vcard.Card) error {
+	var b strings.Builder
+	esc := html.EscapeString
+	value := func(p *vcard.Property) string {
+		return esc(p.GetValueText())
+	}
+	b.WriteString(`<div class="h-card">`+"\n")
+	for _,p := range c[vcard.PropPhoto]{
+		if src := safeURL(p.GetValueText());src != ""{
+			fmt.Fprintf(&b,`  <img class="u-photo" src="%s" alt="">`+"\n",esc(src))
+		}
+	}
+	if fn := c.Get(vcard.PropFN);fn != nil{
+		if u := c.Get(vcard.PropUrl);u != nil && safeURL(u.GetValueText()) != ""{
+			fmt.Fprintf(&b,`  <a class="p-name u-url" href="%s">%s</a>`+"\n",esc(safeURL(u.GetValueText())),value(fn))
+		}else{
+			fmt.Fprintf(&b,`  <span class="p-name">%s</span>`+"\n",value(fn))
+		}
+	}
+	if n := c.Name();n != nil{
+		for _,part := range [][2]string{
+			{"honorific-prefix",n.HonorificPrefix},{"given-name",n.GivenName},
+			{"additional-name",n.AdditionalName},{"family-name",n.FamilyName},
+			{"honorific-suffix",n.HonorificSuffix},
+		}{
+			if part[1] != ""{
+				fmt.Fprintf(&b,`  <data class="p-%s" value="%s"></data>`+"\n",part[0],esc(part[1]))
+			}
+		}
+	}
+	for _,p := range c[vcard.PropNickName]{
+		fmt.Fprintf(&b,`  <span class="p-nickname">%s</span>`+"\n",value(p))
+	}
+	for _,p := range c[vcard.PropTiTle]{
+		fmt.Fprintf(&b,`  <span class="p-job-title">%s</span>`+"\n",value(p))
+	}
+	for _,p := range c[vcard.PropOrg]{
+		fmt.Fprintf(&b,`  <span class="p-org">%s</span>`+"\n",esc(strings.Join(p.GetValueTextList(),", ")))
+	}
+	for _,p := range c[vcard.PropEmail]{
+		fmt.Fprintf(&b,`  <a class="u-email" href="mailto:%s">%s</a>`+"\n",value(p),value(p))
+	}
+	for _,p := range c[vcard.PropTel]{
+		tel := strings.TrimPrefix(p.GetValueText(),"tel:")
+		fmt.Fprintf(&b,`  <a class="p-tel" href="tel:%s">%s</a>`+"\n",esc(strings.Replace(tel," ","",-1)),esc(tel))
+	}
+	for i,u := range c[vcard.PropUrl]{
+		if i == 0 && c.Get(vcard.PropFN) != nil{
+			//written with the name
+			continue
+		}
+		if href := safeURL(u.GetValueText());href != ""{
+			fmt.Fprintf(&b,`  <a class="u-url" href="%s">%s</a>`+"\n",esc(href),value(u))
+		}
+	}
+	for _,adr := range c.Addresses(){
+		b.WriteString(`  <p class="p-adr h-adr">`)
+		sep := ""
+		for _,part := range [][2]string{
+			{"post-office-box",adr.PostOfficeBox},{"extended-address",adr.ExtendedAddress},
+			{"street-address",adr.StreetAddress},{"locality",adr.Locality},{"region",adr.Region},
+			{"postal-code",adr.PostalCode},{"country-name",adr.Country},
+		}{
+			if part[1] != ""{
+				fmt.Fprintf(&b,`%s<span class="p-%s">%s</span>`,sep,part[0],esc(part[1]))
+				sep = ", "
+			}
+		}
+		b.WriteString("</p>\n")
+	}
+	for _,p := range c[vcard.PropBday]{
+		fmt.Fprintf(&b,`  <time class="dt-bday" datetime="%s">%s</time>`+"\n",esc(extendedDate(p.GetValueText())),esc(extendedDate(p.GetValueText())))
+	}
+	for _,p := range c[vcard.PropCategories]{
+		for _,v := range p.GetValueTextList(){
+			fmt.Fprintf(&b,`  <span class="p-category">%s</span>`+"\n",esc(v))
+		}
+	}
+	for _,p := range c[vcard.PropNote]{
+		fmt.Fprintf(&b,`  <p class="p-note">%s</p>`+"\n",value(p))
+	}
+	b.WriteString("</div>\n")
+	_,err := io.WriteString(w,b.String())
+	return err
+}
+
+/*
+the URL if its scheme is safe in href and src:http,https,mailto,tel or a
+data: image,otherwise "",e.g. for javascript:
+ */
+func safeURL(u string) string {
+	u = strings.TrimSpace(u)
+	i := strings.IndexByte(u,':')
+	if i < 0{
+		return ""
+	}
+	switch strings.ToLower(u[:i]) {
+	case "http","https","mailto","tel":
+		return u
+	case "data":
+		if rest := u[i+1:];len(rest) >= 6 && strings.EqualFold(rest[:6],"image/"){
+			return u
+		}
+	}
+	return ""
+}
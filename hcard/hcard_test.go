@@ -0,0 +1,193 @@
+package hcard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	vcard "github.com/ScottAI/go-vcard"
+)
+
+var testHTML = `<!DOCTYPE html>
+<html>
+<head><title>Team &amp; friends</title></head>
+<body>
+<ul>
+<li class="h-card">
+  <img class="u-photo" src="/img/joe.jpg" alt="">
+  <a class="p-name u-url" href="/people/joe">Dr. Joe Bloggs</a>
+  <span class="p-honorific-prefix">Dr.</span>
+  <span class="p-given-name">Joe</span> <span class="p-family-name">Bloggs</span>
+  <br>
+  <a class="u-email" href="mailto:joe@example.com">email me</a>
+  <span class="p-tel">+1 555 0100</span>
+  <p class="p-adr h-adr">
+    <span class="p-street-address">1 Main St</span>,
+    <span class="p-locality">Springfield</span>
+    <span class="p-country-name">USA</span>
+  </p>
+  <time class="dt-bday" datetime="1985-04-12">April 12</time>
+  <span class="p-category">engineering</span><span class="p-category">go</span>
+</li>
+<li class="vcard">
+  <span class="fn n"><span class="given-name">Jane</span> <span class="family-name">Doe</span></span>
+  <span class="tel"><span class="type">work</span>: <span class="value">+1 555 0200</span></span>
+  <div class="adr"><span class="locality">Shelbyville</span>, <abbr class="region" title="Illinois">IL</abbr></div>
+  <span class="org">ACME</span>
+</li>
+</ul>
+</body>
+</html>`
+
+func TestParse(t *testing.T) {
+	cards, err := Parse(strings.NewReader(testHTML), "http://example.com/team/")
+	if err != nil {
+		t.Fatal("Expected no error when parsing h-card, got:", err)
+	}
+	if len(cards) != 2 {
+		t.Fatalf("Expected 2 cards but got %d", len(cards))
+	}
+
+	joe := cards[0]
+	if v := joe.Get(vcard.PropFN).GetValueFirstText(); v != "Dr. Joe Bloggs" {
+		t.Errorf("Expected FN Dr. Joe Bloggs but got %q", v)
+	}
+	if n := joe.Name(); n == nil || n.GivenName != "Joe" || n.FamilyName != "Bloggs" || n.HonorificPrefix != "Dr." {
+		t.Errorf("Expected structured name but got %+v", n)
+	}
+	if v := joe.Get(vcard.PropUrl).GetValueFirstText(); v != "http://example.com/people/joe" {
+		t.Errorf("Expected resolved URL but got %q", v)
+	}
+	if v := joe.Get(vcard.PropPhoto).GetValueFirstText(); v != "http://example.com/img/joe.jpg" {
+		t.Errorf("Expected resolved PHOTO but got %q", v)
+	}
+	if v := joe.Get(vcard.PropEmail).GetValueFirstText(); v != "joe@example.com" {
+		t.Errorf("Expected EMAIL without mailto but got %q", v)
+	}
+	if a := joe.Address(); a == nil || a.StreetAddress != "1 Main St" || a.Locality != "Springfield" || a.Country != "USA" {
+		t.Errorf("Expected h-adr address but got %+v", a)
+	}
+	if v := joe.Get(vcard.PropBday).GetValueFirstText(); v != "19850412" {
+		t.Errorf("Expected BDAY 19850412 but got %q", v)
+	}
+	if v := joe.Get(vcard.PropCategories).GetValueTextList(); len(v) != 2 {
+		t.Errorf("Expected 2 categories but got %q", v)
+	}
+
+	var b bytes.Buffer
+	if err := vcard.NewEncoder(&b).Encode(joe); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "\r\nVERSION:4.0\r\n") {
+		t.Errorf("Expected VERSION:4.0 in %q", b.String())
+	}
+	if got, err := vcard.NewDecoder(&b).Decode(); err != nil {
+		t.Error("Expected no error when decoding the parsed h-card, got:", err)
+	} else if v := got.Get(vcard.PropVersion).GetValueFirstText(); v != "4.0" {
+		t.Errorf("Expected VERSION 4.0 but got %q", v)
+	}
+
+	jane := cards[1]
+	if v := jane.Get(vcard.PropFN).GetValueFirstText(); v != "Jane Doe" {
+		t.Errorf("Expected legacy FN Jane Doe but got %q", v)
+	}
+	if n := jane.Name(); n == nil || n.GivenName != "Jane" || n.FamilyName != "Doe" {
+		t.Errorf("Expected legacy structured name but got %+v", n)
+	}
+	tel := jane.Get(vcard.PropTel)
+	if tel.GetValueFirstText() != "+1 555 0200" || !tel.IsHasType("work") {
+		t.Errorf("Expected legacy work TEL with value class but got %+v", tel)
+	}
+	if a := jane.Address(); a == nil || a.Locality != "Shelbyville" || a.Region != "Illinois" {
+		t.Errorf("Expected legacy adr but got %+v", a)
+	}
+	if v := jane.Get(vcard.PropOrg).GetValueFirstText(); v != "ACME" {
+		t.Errorf("Expected legacy ORG but got %q", v)
+	}
+}
+
+func TestRender(t *testing.T) {
+	card := make(vcard.Card)
+	card.SetValue(vcard.PropVersion, [][]string{{"4.0"}})
+	card.SetValue(vcard.PropFN, [][]string{{"Joe <Bloggs>"}})
+	card.AddName(&vcard.Name{FamilyName: "Bloggs", GivenName: "Joe"})
+	card.SetValue(vcard.PropEmail, [][]string{{"joe@example.com"}})
+	card.SetValue(vcard.PropPhoto, [][]string{{"http://example.com/joe.jpg"}})
+	card.AddAdress(&vcard.Address{StreetAddress: "1 Main St", Locality: "Springfield"})
+
+	var b bytes.Buffer
+	if err := Render(&b, card); err != nil {
+		t.Fatal("Expected no error when rendering h-card, got:", err)
+	}
+	out := b.String()
+	for _, s := range []string{
+		`<span class="p-name">Joe &lt;Bloggs&gt;</span>`,
+		`<a class="u-email" href="mailto:joe@example.com">`,
+		`<img class="u-photo" src="http://example.com/joe.jpg"`,
+		`<p class="p-adr h-adr"><span class="p-street-address">1 Main St</span>, <span class="p-locality">Springfield</span></p>`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected h-card to contain %q but got:\n%s", s, out)
+		}
+	}
+
+	cards, err := Parse(&b, "")
+	if err != nil || len(cards) != 1 {
+		t.Fatalf("Expected rendered h-card to be parsed but got %d cards (%v)", len(cards), err)
+	}
+	got := cards[0]
+	if v := got.Get(vcard.PropFN).GetValueFirstText(); v != "Joe <Bloggs>" {
+		t.Errorf("Expected FN to round-trip but got %q", v)
+	}
+	if n := got.Name(); n == nil || n.GivenName != "Joe" || n.FamilyName != "Bloggs" {
+		t.Errorf("Expected N to round-trip but got %+v", n)
+	}
+	if a := got.Address(); a == nil || a.StreetAddress != "1 Main St" || a.Locality != "Springfield" {
+		t.Errorf("Expected ADR to round-trip but got %+v", a)
+	}
+}
+
+func TestParse_rawText(t *testing.T) {
+	input := `<html><head><SCRIPT type="text/javascript">if (a < b && c) { x = "</div>" }</SCRIPT>
+<style>a > b { color: red }</style></head>
+<body><div class="h-card"><span class="p-name">Joe Bloggs</span></div></body></html>`
+	cards, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal("Expected no error when parsing HTML with a script, got:", err)
+	}
+	if len(cards) != 1 || cards[0].Get(vcard.PropFN).GetValueFirstText() != "Joe Bloggs" {
+		t.Errorf("Expected the h-card after the script but got %v", cards)
+	}
+
+	if _, err := Parse(strings.NewReader(`<p>1 < 2</p><div class="h-card"><span class="p-name">Joe</span></div>`), ""); err == nil {
+		t.Error("Expected an error for HTML that cannot be parsed")
+	}
+}
+
+func TestRender_unsafeURL(t *testing.T) {
+	card := make(vcard.Card)
+	card.SetValue(vcard.PropFN, [][]string{{"Joe Bloggs"}})
+	card.Add(vcard.PropUrl, &vcard.Property{Name: vcard.PropUrl, Value: [][]string{{"javascript:alert(1)"}}})
+	card.Add(vcard.PropUrl, &vcard.Property{Name: vcard.PropUrl, Value: [][]string{{" JavaScript:alert(2)"}}})
+	card.Add(vcard.PropUrl, &vcard.Property{Name: vcard.PropUrl, Value: [][]string{{"https://example.com/joe"}}})
+	card.Add(vcard.PropPhoto, &vcard.Property{Name: vcard.PropPhoto, Value: [][]string{{"data:text/html"}, {"base64", "PHNjcmlwdD4="}}})
+	card.Add(vcard.PropPhoto, &vcard.Property{Name: vcard.PropPhoto, Value: [][]string{{"data:image/png"}, {"base64", "iVBORw0KGgo="}}})
+
+	var b bytes.Buffer
+	if err := Render(&b, card); err != nil {
+		t.Fatal("Expected no error when rendering h-card, got:", err)
+	}
+	out := b.String()
+	if strings.Contains(strings.ToLower(out), "javascript:") || strings.Contains(out, "data:text/html") {
+		t.Errorf("Expected unsafe URLs to be dropped but got:\n%s", out)
+	}
+	for _, s := range []string{
+		`<span class="p-name">Joe Bloggs</span>`,
+		`<a class="u-url" href="https://example.com/joe">`,
+		`<img class="u-photo" src="data:image/png;base64,iVBORw0KGgo="`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected h-card to contain %q but got:\n%s", s, out)
+		}
+	}
+}
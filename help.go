@@ -1,5 +1,7 @@
 package go_vcard
 
+import "strings"
+
 func MatrixToString(vals [][]string) string {
 	end := ""
 	for _,line := range vals{
@@ -18,3 +20,26 @@ func MatrixToString(vals [][]string) string {
 	}
 	return end
 }
+
+/*
+join a value back to its text form,components by ';' and lists by ','
+ */
+func joinValue(vals [][]string) string {
+	comps := make([]string,len(vals))
+	for i,line := range vals{
+		comps[i] = strings.Join(line,",")
+	}
+	return strings.Join(comps,";")
+}
+
+/*
+split a joined value on ';' and ',' without unescaping,the inverse of joinValue
+ */
+func splitValue(s string) [][]string {
+	comps := strings.Split(s,";")
+	vals := make([][]string,len(comps))
+	for i,comp := range comps{
+		vals[i] = strings.Split(comp,",")
+	}
+	return vals
+}
@@ -0,0 +1,445 @@
+package go_vcard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//https://tools.ietf.org/html/rfc7095
+//jCard,the JSON format for vCard
+
+/*
+encode a card as jCard:["vcard",[[name,params,type,value...],...]]
+ */
+func MarshalJCard(c Card) ([]byte,error) {
+	v,err := c.jcard()
+	if err != nil{
+		return nil,err
+	}
+	return json.Marshal(v)
+}
+
+/*
+encode cards as a jCard array:[["vcard",[...]],["vcard",[...]]]
+ */
+func MarshalJCards(cards []Card) ([]byte,error) {
+	vs := make([]interface{},len(cards))
+	for i,c := range cards{
+		v,err := c.jcard()
+		if err != nil{
+			return nil,fmt.Errorf("vcard:card %d:%v",i,err)
+		}
+		vs[i] = v
+	}
+	return json.Marshal(vs)
+}
+
+/*
+decode a single jCard
+ */
+func UnmarshalJCard(data []byte) (Card,error) {
+	v,err := decodeJSON(data)
+	if err != nil{
+		return nil,err
+	}
+	return cardOfJCard(v)
+}
+
+/*
+decode a jCard array,a single jCard is accepted too
+ */
+func UnmarshalJCards(data []byte) ([]Card,error) {
+	v,err := decodeJSON(data)
+	if err != nil{
+		return nil,err
+	}
+	arr,ok := v.([]interface{})
+	if !ok{
+		return nil,errors.New("vcard:invalid jCard:not an array")
+	}
+	if len(arr) > 0{
+		if s,ok := arr[0].(string);ok && strings.EqualFold(s,"vcard"){
+			c,err := cardOfJCard(v)
+			if err != nil{
+				return nil,err
+			}
+			return []Card{c},nil
+		}
+	}
+	cards := make([]Card,len(arr))
+	for i,v := range arr{
+		if cards[i],err = cardOfJCard(v);err != nil{
+			return nil,fmt.Errorf("vcard:card %d:%v",i,err)
+		}
+	}
+	return cards,nil
+}
+
+/*
+encode a single property as a jCard property:[name,params,type,value...]
+ */
+func MarshalJCardProperty(p *Property) ([]byte,error) {
+	if p.Name == ""{
+		return nil,errors.New("vcard:property name missing")
+	}
+	return json.Marshal(jcardProperty(p))
+}
+
+/*
+decode a single jCard property
+ */
+func UnmarshalJCardProperty(data []byte) (*Property,error) {
+	v,err := decodeJSON(data)
+	if err != nil{
+		return nil,err
+	}
+	p,err := propertyOfJCard(v)
+	if err != nil{
+		return nil,fmt.Errorf("vcard:invalid jCard:%v",err)
+	}
+	return p,nil
+}
+
+/*
+Card encode as jCard in JSON
+ */
+func (c Card) MarshalJSON() ([]byte,error) {
+	return MarshalJCard(c)
+}
+
+func (c *Card) UnmarshalJSON(data []byte) error {
+	card,err := UnmarshalJCard(data)
+	if err != nil{
+		return err
+	}
+	*c = card
+	return nil
+}
+
+func decodeJSON(data []byte) (interface{},error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v);err != nil{
+		return nil,err
+	}
+	return v,nil
+}
+
+/*
+properties with a structured value in jCard,the components of other
+properties are joined in a single value
+ */
+var jcardStructured = map[string]bool{
+	PropN: true,
+	PropAdr: true,
+	PropOrg: true,
+	PropGender: true,
+	PropClientPidmap: true,
+}
+
+/*
+properties whose text value is a list,each item is a jCard value
+ */
+var jcardLists = map[string]bool{
+	PropNickName: true,
+	PropCategories: true,
+}
+
+func (c Card) jcard() ([]interface{},error) {
+	if c.Get(PropVersion) == nil{
+		return nil,errors.New("VCARD: Version property missing")
+	}
+	var jprops []interface{}
+	for _,p := range c.Properties(){
+		jprops = append(jprops,jcardProperty(p))
+	}
+	return []interface{}{"vcard",jprops},nil
+}
+
+func jcardProperty(p *Property) []interface{} {
+	typ := p.ValueType()
+	params := make(map[string]interface{})
+	if p.Group != ""{
+		params["group"] = p.Group
+	}
+	for k,vals := range p.Params{
+		if strings.EqualFold(k,ParamValue) && len(vals) > 0{
+			continue
+		}
+		if len(vals) == 1{
+			params[strings.ToLower(k)] = vals[0]
+		}else{
+			params[strings.ToLower(k)] = vals
+		}
+	}
+	jp := []interface{}{strings.ToLower(p.Name),params,typ}
+	name := strings.ToUpper(p.Name)
+	if len(p.Value) > 1 && jcardStructured[name]{
+		//structured value,e.g. N or ADR
+		comps := make([]interface{},len(p.Value))
+		for i,vals := range p.Value{
+			if len(vals) == 1{
+				comps[i] = jcardValue(typ,vals[0])
+				continue
+			}
+			list := make([]interface{},len(vals))
+			for vi,v := range vals{
+				list[vi] = jcardValue(typ,v)
+			}
+			comps[i] = list
+		}
+		return append(jp,comps)
+	}
+	if len(p.Value) == 0 || len(p.Value[0]) == 0{
+		return append(jp,"")
+	}
+	if len(p.Value) > 1 || !jcardLists[name] || typ != "text"{
+		//the ';' and ',' of a single value,e.g. a geo: URI or a data: URI
+		return append(jp,jcardValue(typ,joinValue(p.Value)))
+	}
+	for _,v := range p.Value[0]{
+		jp = append(jp,jcardValue(typ,v))
+	}
+	return jp
+}
+
+/*
+jCard value of a text value:numbers and booleans are JSON types,
+date and time use the extended ISO 8601 form
+ */
+func jcardValue(typ,v string) interface{} {
+	switch typ {
+	case "integer":
+		if _,err := strconv.ParseInt(v,10,64);err == nil{
+			return json.Number(v)
+		}
+	case "float":
+		if _,err := strconv.ParseFloat(v,64);err == nil{
+			return json.Number(v)
+		}
+	case "boolean":
+		if b,err := strconv.ParseBool(strings.ToLower(v));err == nil{
+			return b
+		}
+	case "date","time","date-time","date-and-or-time","timestamp":
+		return extendedDateTime(typ,v)
+	case "utc-offset":
+		return extendedZone(v)
+	}
+	return v
+}
+
+func cardOfJCard(v interface{}) (Card,error) {
+	arr,ok := v.([]interface{})
+	if !ok || len(arr) != 2{
+		return nil,errors.New("vcard:invalid jCard:expected [\"vcard\",[...]]")
+	}
+	if s,ok := arr[0].(string);!ok || !strings.EqualFold(s,"vcard"){
+		return nil,errors.New("vcard:invalid jCard:expected \"vcard\"")
+	}
+	jprops,ok := arr[1].([]interface{})
+	if !ok{
+		return nil,errors.New("vcard:invalid jCard:properties is not an array")
+	}
+	c := make(Card)
+	for i,jp := range jprops{
+		p,err := propertyOfJCard(jp)
+		if err != nil{
+			return nil,fmt.Errorf("vcard:invalid jCard:property %d:%v",i,err)
+		}
+		c.Add(p.Name,p)
+	}
+	return c,nil
+}
+
+func propertyOfJCard(v interface{}) (*Property,error) {
+	arr,ok := v.([]interface{})
+	if !ok || len(arr) < 4{
+		return nil,errors.New("expected [name,params,type,value...]")
+	}
+	name,ok := arr[0].(string)
+	if !ok || name == ""{
+		return nil,errors.New("name is not a string")
+	}
+	jparams,ok := arr[1].(map[string]interface{})
+	if !ok{
+		return nil,errors.New("params is not an object")
+	}
+	typ,ok := arr[2].(string)
+	if !ok{
+		return nil,errors.New("type is not a string")
+	}
+	p := &Property{Name:strings.ToUpper(name)}
+	for k,jv := range jparams{
+		if strings.EqualFold(k,"group"){
+			p.Group,_ = jv.(string)
+			continue
+		}
+		switch jv := jv.(type) {
+		case []interface{}:
+			for _,e := range jv{
+				p.AddParam(strings.ToUpper(k),jsonString("",e))
+			}
+		default:
+			p.AddParam(strings.ToUpper(k),jsonString("",jv))
+		}
+	}
+	if typ = strings.ToLower(typ);typ != defaultValueType(name) && typ != "unknown"{
+		p.SetParam(ParamValue,typ)
+	}
+
+	values := arr[3:]
+	if comps,ok := values[0].([]interface{});ok && len(values) == 1{
+		//structured value
+		p.Value = make([][]string,len(comps))
+		for i,comp := range comps{
+			if list,ok := comp.([]interface{});ok{
+				p.Value[i] = make([]string,len(list))
+				for vi,e := range list{
+					p.Value[i][vi] = jsonString(typ,e)
+				}
+			}else{
+				p.Value[i] = []string{jsonString(typ,comp)}
+			}
+		}
+		return p,nil
+	}
+	if len(values) == 1 && typ != "text"{
+		//split as the text decoder do,e.g. a data: URI
+		p.Value = splitValue(jsonString(typ,values[0]))
+		return p,nil
+	}
+	vals := make([]string,len(values))
+	for i,e := range values{
+		vals[i] = jsonString(typ,e)
+	}
+	p.Value = [][]string{vals}
+	return p,nil
+}
+
+
+/*
+text value of a jCard JSON value
+ */
+func jsonString(typ string,v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		switch typ {
+		case "date","time","date-time","date-and-or-time","timestamp":
+			return basicDateTime(typ,v)
+		case "utc-offset":
+			return strings.Replace(v,":","",-1)
+		}
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+/*
+convert a date,time or date-time from the basic ISO 8601 form of vCard text
+to the extended form of jCard,e.g. 19850412 to 1985-04-12
+ */
+func extendedDateTime(typ,s string) string {
+	if typ == "time"{
+		return extendedTime(s)
+	}
+	date,tm := s,""
+	i := strings.IndexByte(s,'T')
+	if i >= 0{
+		date,tm = s[:i],s[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(date,"---"):
+	case strings.HasPrefix(date,"--"):
+		if len(date) == 6{
+			date = date[:4]+"-"+date[4:]
+		}
+	case len(date) == 8 && isDigits(date):
+		date = date[:4]+"-"+date[4:6]+"-"+date[6:]
+	}
+	if i < 0{
+		return date
+	}
+	return date+"T"+extendedTime(tm)
+}
+
+/*
+e.g. 102200-0800 to 10:22:00-08:00,-2200 to -22:00
+ */
+func extendedTime(t string) string {
+	//leading '-' stand for omitted hour or minute
+	n := 0
+	for n < len(t) && t[n] == '-'{
+		n++
+	}
+	end := len(t)
+	if z := strings.IndexAny(t[n:],"Z+-");z >= 0{
+		end = n+z
+	}
+	var pairs []string
+	for i := n;i < end;i += 2{
+		j := i+2
+		if j > end{
+			j = end
+		}
+		pairs = append(pairs,t[i:j])
+	}
+	return t[:n]+strings.Join(pairs,":")+extendedZone(t[end:])
+}
+
+/*
+e.g. -0500 to -05:00
+ */
+func extendedZone(z string) string {
+	if len(z) == 5 && (z[0] == '+' || z[0] == '-'){
+		return z[:3]+":"+z[3:]
+	}
+	return z
+}
+
+/*
+convert a date,time or date-time from the extended ISO 8601 form of jCard
+to the basic form of vCard text,e.g. 1985-04-12 to 19850412
+ */
+func basicDateTime(typ,s string) string {
+	if typ == "time"{
+		return strings.Replace(s,":","",-1)
+	}
+	date,tm := s,""
+	i := strings.IndexByte(s,'T')
+	if i >= 0{
+		date,tm = s[:i],s[i+1:]
+	}
+	n := 0
+	for n < len(date) && date[n] == '-'{
+		n++
+	}
+	//YYYY-MM has no basic form
+	if !(n == 0 && len(date) == 7 && date[4] == '-'){
+		date = date[:n]+strings.Replace(date[n:],"-","",-1)
+	}
+	if i < 0{
+		return date
+	}
+	return date+"T"+strings.Replace(tm,":","",-1)
+}
+
+func isDigits(s string) bool {
+	for i := 0;i < len(s);i++{
+		if s[i] < '0' || s[i] > '9'{
+			return false
+		}
+	}
+	return true
+}
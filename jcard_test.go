@@ -0,0 +1,169 @@
+package go_vcard
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJCard(t *testing.T) {
+	card := Card{
+		"VERSION": []*Property{{Name: "VERSION", Value: [][]string{{"4.0"}}}},
+		"FN":      []*Property{{Name: "FN", Value: [][]string{{"J. Doe"}}}},
+		"N":       []*Property{{Name: "N", Value: [][]string{{"Doe"}, {"J."}, {""}, {"Mr.", "Dr."}, {""}}}},
+		"BDAY":    []*Property{{Name: "BDAY", Value: [][]string{{"--0412"}}}},
+		"EMAIL": []*Property{{Name: "EMAIL",
+			Group:  "item1",
+			Value:  [][]string{{"jdoe@example.com"}},
+			Params: map[string][]string{"TYPE": {"work", "home"}},
+		}},
+		"X-INDEX": []*Property{{Name: "X-INDEX", Value: [][]string{{"42"}}, Params: map[string][]string{"VALUE": {"integer"}}}},
+	}
+
+	b, err := MarshalJCard(card)
+	if err != nil {
+		t.Fatal("Expected no error when marshaling jCard, got:", err)
+	}
+	expected := `["vcard",[["version",{},"text","4.0"],` +
+		`["bday",{},"date-and-or-time","--04-12"],` +
+		`["email",{"group":"item1","type":["work","home"]},"text","jdoe@example.com"],` +
+		`["fn",{},"text","J. Doe"],` +
+		`["n",{},"text",["Doe","J.","",["Mr.","Dr."],""]],` +
+		`["x-index",{},"integer",42]]]`
+	if string(b) != expected {
+		t.Errorf("Expected jCard to be\n%s\n but got\n%s", expected, string(b))
+	}
+
+	decoded, err := UnmarshalJCard(b)
+	if err != nil {
+		t.Fatal("Expected no error when unmarshaling jCard, got:", err)
+	}
+	if !reflect.DeepEqual(decoded, card) {
+		t.Errorf("Invalid unmarshaled card: expected %+v but got %+v", card, decoded)
+	}
+}
+
+func TestJCard_roundTrip(t *testing.T) {
+	b, err := json.Marshal([]Card{testCard})
+	if err != nil {
+		t.Fatal("Expected no error when marshaling cards, got:", err)
+	}
+	var cards []Card
+	if err := json.Unmarshal(b, &cards); err != nil {
+		t.Fatal("Expected no error when unmarshaling cards, got:", err)
+	}
+	if len(cards) != 1 || !reflect.DeepEqual(cards[0], testCard) {
+		t.Fatalf("Invalid unmarshaled cards: expected %+v but got %+v", testCard, cards)
+	}
+
+	var text bytes.Buffer
+	if err := NewEncoder(&text).Encode(cards[0]); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	card, err := NewDecoder(&text).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding card, got:", err)
+	}
+	if !reflect.DeepEqual(card, testCard) {
+		t.Errorf("Invalid decoded card: expected %+v but got %+v", testCard, card)
+	}
+}
+
+func TestUnmarshalJCards(t *testing.T) {
+	b, err := MarshalJCards([]Card{testCard, testCard})
+	if err != nil {
+		t.Fatal("Expected no error when marshaling cards, got:", err)
+	}
+	cards, err := UnmarshalJCards(b)
+	if err != nil {
+		t.Fatal("Expected no error when unmarshaling cards, got:", err)
+	}
+	if len(cards) != 2 {
+		t.Errorf("Expected 2 cards but got %d", len(cards))
+	}
+
+	single, _ := MarshalJCard(testCard)
+	if cards, err := UnmarshalJCards(single); err != nil || len(cards) != 1 {
+		t.Errorf("Expected a single jCard to be accepted but got %d cards and %v", len(cards), err)
+	}
+
+	for _, invalid := range []string{`{}`, `["vcard"]`, `["vcard",[["fn",{},"text"]]]`, `["vcard",[["fn",[],"text","x"]]]`} {
+		if _, err := UnmarshalJCard([]byte(invalid)); err == nil || !strings.HasPrefix(err.Error(), "vcard:") {
+			t.Errorf("Expected an error when unmarshaling %s but got %v", invalid, err)
+		}
+	}
+}
+
+func TestJCard_dateTime(t *testing.T) {
+	tests := []struct {
+		typ, basic, extended string
+	}{
+		{"date-and-or-time", "19850412", "1985-04-12"},
+		{"date-and-or-time", "1985-04", "1985-04"},
+		{"date-and-or-time", "---12", "---12"},
+		{"date-and-or-time", "T102200Z", "T10:22:00Z"},
+		{"date-time", "19961022T140000-0500", "1996-10-22T14:00:00-05:00"},
+		{"time", "-2200", "-22:00"},
+		{"timestamp", "20130115T235959Z", "2013-01-15T23:59:59Z"},
+	}
+	for _, test := range tests {
+		if v := extendedDateTime(test.typ, test.basic); v != test.extended {
+			t.Errorf("Expected %q to be %q but got %q", test.basic, test.extended, v)
+		}
+		if v := basicDateTime(test.typ, test.extended); v != test.basic {
+			t.Errorf("Expected %q to be %q but got %q", test.extended, test.basic, v)
+		}
+	}
+}
+
+func TestJCard_singleValues(t *testing.T) {
+	input := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:J. Doe\r\n" +
+		"GEO:geo:37.386013,-122.082932\r\n" +
+		"PHOTO:data:image/png;base64,iVBORw0KGgo=\r\n" +
+		"TEL;VALUE=uri:tel:+1-555-555-5555;ext=5555\r\n" +
+		"NOTE:a\\, b\\; c\r\n" +
+		"CATEGORIES:swimmer,biker\r\n" +
+		"END:VCARD\r\n"
+	card, err := NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := MarshalJCard(card)
+	if err != nil {
+		t.Fatal("Expected no error when marshaling jCard, got:", err)
+	}
+	for _, s := range []string{
+		`["geo",{},"uri","geo:37.386013,-122.082932"]`,
+		`["photo",{},"uri","data:image/png;base64,iVBORw0KGgo="]`,
+		`["tel",{},"uri","tel:+1-555-555-5555;ext=5555"]`,
+		`["note",{},"text","a, b; c"]`,
+		`["categories",{},"text","swimmer","biker"]`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("Expected jCard to contain %s but got\n%s", s, string(b))
+		}
+	}
+
+	decoded, err := UnmarshalJCard(b)
+	if err != nil {
+		t.Fatal("Expected no error when unmarshaling jCard, got:", err)
+	}
+	var text bytes.Buffer
+	if err := NewEncoder(&text).Encode(decoded); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(input, "\r\n"), "\r\n") {
+		if !strings.Contains(text.String(), line+"\r\n") {
+			t.Errorf("Expected %q after a jCard round trip but got\n%s", line, text.String())
+		}
+	}
+	if g, err := decoded.Geo(); err != nil || g.Latitude != 37.386013 {
+		t.Errorf("Expected GEO after a jCard round trip but got %+v (%v)", g, err)
+	}
+	if photos, err := decoded.Photos(); err != nil || len(photos) != 1 || photos[0].MediaType != "image/png" {
+		t.Errorf("Expected inline PHOTO after a jCard round trip but got %+v (%v)", photos, err)
+	}
+}
@@ -0,0 +1,1035 @@
+//https://tools.ietf.org/html/rfc9553
+//https://tools.ietf.org/html/rfc9555
+//JSContact,the JSON contact format,and its conversion from and to vCard
+package jscontact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	vcard "github.com/ScottAI/go-vcard"
+)
+
+/*
+a JSContact Card
+ */
+type Card struct {
+	Type string `json:"@type"`
+	Version string `json:"version"`
+	UID string `json:"uid,omitempty"`
+	Kind string `json:"kind,omitempty"`
+	Language string `json:"language,omitempty"`
+	Name *Name `json:"name,omitempty"`
+	Nicknames map[string]*Nickname `json:"nicknames,omitempty"`
+	Organizations map[string]*Organization `json:"organizations,omitempty"`
+	Titles map[string]*Title `json:"titles,omitempty"`
+	Emails map[string]*Email `json:"emails,omitempty"`
+	Phones map[string]*Phone `json:"phones,omitempty"`
+	OnlineServices map[string]*OnlineService `json:"onlineServices,omitempty"`
+	Addresses map[string]*Address `json:"addresses,omitempty"`
+	Notes map[string]*Note `json:"notes,omitempty"`
+	Keywords map[string]bool `json:"keywords,omitempty"`
+
+	//language tag to patch,the patch map a JSON pointer to the localized value
+	Localizations map[string]map[string]json.RawMessage `json:"localizations,omitempty"`
+
+	//properties that have no JSContact mapping,as jCard properties
+	VCardProps []json.RawMessage `json:"vCardProps,omitempty"`
+}
+
+/*
+vCard params without JSContact mapping,a single value is written as a string
+ */
+type Params map[string][]string
+
+func (ps Params) MarshalJSON() ([]byte,error) {
+	m := make(map[string]interface{},len(ps))
+	for k,vals := range ps{
+		if len(vals) == 1{
+			m[k] = vals[0]
+		}else{
+			m[k] = vals
+		}
+	}
+	return json.Marshal(m)
+}
+
+func (ps *Params) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data,&m);err != nil{
+		return err
+	}
+	*ps = make(Params,len(m))
+	for k,raw := range m{
+		var s string
+		if err := json.Unmarshal(raw,&s);err == nil{
+			(*ps)[k] = []string{s}
+			continue
+		}
+		var vals []string
+		if err := json.Unmarshal(raw,&vals);err != nil{
+			return fmt.Errorf("jscontact:vCardParams %q:%v",k,err)
+		}
+		(*ps)[k] = vals
+	}
+	return nil
+}
+
+/*
+fields shared by the objects converted from a vCard property
+ */
+type Common struct {
+	Contexts map[string]bool `json:"contexts,omitempty"`
+	Pref int `json:"pref,omitempty"`
+	VCardParams Params `json:"vCardParams,omitempty"`
+}
+
+type Name struct {
+	Components []NameComponent `json:"components,omitempty"`
+	Full string `json:"full,omitempty"`
+	VCardParams Params `json:"vCardParams,omitempty"`
+}
+
+type NameComponent struct {
+	Kind string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Nickname struct {
+	Name string `json:"name"`
+	Common
+}
+
+type Organization struct {
+	Name string `json:"name,omitempty"`
+	Units []OrgUnit `json:"units,omitempty"`
+	Common
+}
+
+type OrgUnit struct {
+	Name string `json:"name"`
+}
+
+type Title struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+	Common
+}
+
+type Email struct {
+	Address string `json:"address"`
+	Common
+}
+
+type Phone struct {
+	Number string `json:"number"`
+	Features map[string]bool `json:"features,omitempty"`
+	Common
+}
+
+type OnlineService struct {
+	URI string `json:"uri,omitempty"`
+	VCardName string `json:"vCardName,omitempty"`
+	Common
+}
+
+type Address struct {
+	Components []AddressComponent `json:"components,omitempty"`
+	Full string `json:"full,omitempty"`
+	Common
+}
+
+type AddressComponent struct {
+	Kind string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Note struct {
+	Note string `json:"note"`
+	Common
+}
+
+//kinds of the N components in order
+var nameKinds = []string{"surname","given","given2","title","credential"}
+
+//kinds of the ADR components in order
+var addressKinds = []string{"postOfficeBox","apartment","name","locality","region","postcode","country"}
+
+//vCard TYPE to JSContact context
+var contexts = map[string]string{"work":"work","home":"private"}
+
+//vCard TEL TYPE to JSContact phone feature
+var features = map[string]string{
+	"voice":"voice","cell":"mobile","fax":"fax","text":"text",
+	"video":"video","pager":"pager","textphone":"textphone",
+}
+
+//collection of the objects converted from a property
+var collections = map[string]string{
+	vcard.PropNickName:"nicknames",
+	vcard.PropOrg:"organizations",
+	vcard.PropTiTle:"titles",
+	vcard.PropRole:"titles",
+	vcard.PropEmail:"emails",
+	vcard.PropTel:"phones",
+	vcard.PropImpp:"onlineServices",
+	vcard.PropAdr:"addresses",
+	vcard.PropNote:"notes",
+}
+
+//prefix of generated object ids
+var idPrefixes = map[string]string{
+	"nicknames":"k","organizations":"o","titles":"t","emails":"e",
+	"phones":"p","onlineServices":"s","addresses":"a","notes":"n",
+}
+
+/*
+convert a card to JSContact following RFC 9555.the properties of an ALTID
+with a LANGUAGE param are localizations of the first property of that ALTID,
+the LANGUAGE of the FN in name.full is the language of the card.the
+properties without mapping are kept in VCardProps
+ */
+func FromVCard(c vcard.Card) (*Card,error) {
+	if c.Get(vcard.PropVersion) == nil{
+		return nil,errors.New("VCARD: Version property missing")
+	}
+	jc := &Card{Type:"Card",Version:"1.0"}
+	pointers := make(map[string]string)
+	for _,p := range c.Properties(){
+		name := strings.ToUpper(p.Name)
+		if name == vcard.PropVersion{
+			continue
+		}
+		altID := p.GetFirstParamVal(vcard.ParamAltid)
+		lang := p.GetFirstParamVal(vcard.ParamLanguage)
+		if ptr,ok := pointers[name+";"+altID];ok && lang != ""{
+			if err := jc.localize(lang,ptr,p);err != nil{
+				return nil,err
+			}
+			continue
+		}
+		ptr := jc.add(name,p)
+		if ptr == ""{
+			raw,err := vcard.MarshalJCardProperty(p)
+			if err != nil{
+				return nil,err
+			}
+			jc.VCardProps = append(jc.VCardProps,raw)
+			continue
+		}
+		if altID != ""{
+			pointers[name+";"+altID] = ptr
+		}
+	}
+	return jc,nil
+}
+
+/*
+add the property to the card,return the JSON pointer of its value or
+"" when the property has no mapping
+ */
+func (jc *Card) add(name string,p *vcard.Property) string {
+	switch name {
+	case vcard.PropFN:
+		if jc.Name != nil && jc.Name.Full != "" || !onlyParams(p,vcard.ParamAltid,vcard.ParamLanguage){
+			return ""
+		}
+		if lang := p.GetFirstParamVal(vcard.ParamLanguage);lang != ""{
+			if jc.Language != "" && !strings.EqualFold(jc.Language,lang){
+				return ""
+			}
+			jc.Language = lang
+		}
+		if jc.Name == nil{
+			jc.Name = &Name{}
+		}
+		jc.Name.Full = text(p)
+		return "name/full"
+	case vcard.PropN:
+		if jc.Name != nil && jc.Name.Components != nil{
+			return ""
+		}
+		if jc.Name == nil{
+			jc.Name = &Name{}
+		}
+		jc.Name.Components = nameComponents(p)
+		//N has no contexts or pref,all of its params are kept
+		ps := make(Params)
+		for k,vals := range p.Params{
+			ps[strings.ToLower(k)] = vals
+		}
+		if p.Group != ""{
+			ps["group"] = []string{p.Group}
+		}
+		if len(ps) > 0{
+			jc.Name.VCardParams = ps
+		}
+		return "name/components"
+	case vcard.PropUid,vcard.PropKind:
+		if len(p.Params) > 0 || p.Group != ""{
+			return ""
+		}
+		if name == vcard.PropUid{
+			if jc.UID != ""{
+				return ""
+			}
+			jc.UID = text(p)
+			return "uid"
+		}
+		if jc.Kind != ""{
+			return ""
+		}
+		jc.Kind = text(p)
+		return "kind"
+	case vcard.PropCategories:
+		if len(p.Params) > 0 || p.Group != "" || jc.Keywords != nil{
+			return ""
+		}
+		jc.Keywords = make(map[string]bool)
+		for _,v := range p.GetValueTextList(){
+			jc.Keywords[v] = true
+		}
+		return "keywords"
+	case vcard.PropNickName:
+		//a list of nicknames is kept as a vCard property
+		vals := p.GetValueTextList()
+		if len(vals) != 1{
+			return ""
+		}
+	}
+	coll,ok := collections[name]
+	if !ok{
+		return ""
+	}
+	id := idPrefixes[coll]+strconv.Itoa(jc.count(coll)+1)
+	switch v := object(name,p).(type) {
+	case *Nickname:
+		if jc.Nicknames == nil{
+			jc.Nicknames = make(map[string]*Nickname)
+		}
+		jc.Nicknames[id] = v
+	case *Organization:
+		if jc.Organizations == nil{
+			jc.Organizations = make(map[string]*Organization)
+		}
+		jc.Organizations[id] = v
+	case *Title:
+		if jc.Titles == nil{
+			jc.Titles = make(map[string]*Title)
+		}
+		jc.Titles[id] = v
+	case *Email:
+		if jc.Emails == nil{
+			jc.Emails = make(map[string]*Email)
+		}
+		jc.Emails[id] = v
+	case *Phone:
+		if jc.Phones == nil{
+			jc.Phones = make(map[string]*Phone)
+		}
+		jc.Phones[id] = v
+	case *OnlineService:
+		if jc.OnlineServices == nil{
+			jc.OnlineServices = make(map[string]*OnlineService)
+		}
+		jc.OnlineServices[id] = v
+	case *Address:
+		if jc.Addresses == nil{
+			jc.Addresses = make(map[string]*Address)
+		}
+		jc.Addresses[id] = v
+	case *Note:
+		if jc.Notes == nil{
+			jc.Notes = make(map[string]*Note)
+		}
+		jc.Notes[id] = v
+	}
+	return coll+"/"+id
+}
+
+func (jc *Card) count(coll string) int {
+	switch coll {
+	case "nicknames":
+		return len(jc.Nicknames)
+	case "organizations":
+		return len(jc.Organizations)
+	case "titles":
+		return len(jc.Titles)
+	case "emails":
+		return len(jc.Emails)
+	case "phones":
+		return len(jc.Phones)
+	case "onlineServices":
+		return len(jc.OnlineServices)
+	case "addresses":
+		return len(jc.Addresses)
+	case "notes":
+		return len(jc.Notes)
+	}
+	return 0
+}
+
+/*
+add the localized value of the property at ptr
+ */
+func (jc *Card) localize(lang,ptr string,p *vcard.Property) error {
+	q := *p
+	q.Params = make(map[string][]string,len(p.Params))
+	for k,vals := range p.Params{
+		if k = strings.ToUpper(k);k != vcard.ParamAltid && k != vcard.ParamLanguage{
+			q.Params[k] = vals
+		}
+	}
+	var v interface{}
+	switch ptr {
+	case "name/full":
+		v = text(&q)
+	case "name/components":
+		v = nameComponents(&q)
+	default:
+		v = object(strings.ToUpper(p.Name),&q)
+	}
+	raw,err := json.Marshal(v)
+	if err != nil{
+		return err
+	}
+	if jc.Localizations == nil{
+		jc.Localizations = make(map[string]map[string]json.RawMessage)
+	}
+	if jc.Localizations[lang] == nil{
+		jc.Localizations[lang] = make(map[string]json.RawMessage)
+	}
+	jc.Localizations[lang][ptr] = raw
+	return nil
+}
+
+/*
+the JSContact object of a property of the collections
+ */
+func object(name string,p *vcard.Property) interface{} {
+	switch name {
+	case vcard.PropNickName:
+		return &Nickname{Name:text(p),Common:common(p)}
+	case vcard.PropOrg:
+		org := &Organization{Common:common(p)}
+		for i,vals := range p.Value{
+			if i == 0{
+				org.Name = strings.Join(vals,",")
+				continue
+			}
+			org.Units = append(org.Units,OrgUnit{Name:strings.Join(vals,",")})
+		}
+		return org
+	case vcard.PropTiTle,vcard.PropRole:
+		return &Title{Name:text(p),Kind:strings.ToLower(name),Common:common(p)}
+	case vcard.PropEmail:
+		return &Email{Address:text(p),Common:common(p)}
+	case vcard.PropTel:
+		ph := &Phone{Number:text(p),Common:common(p)}
+		//TEL types that are phone features are moved from the params
+		var types []string
+		for _,t := range ph.VCardParams["type"]{
+			if f,ok := features[t];ok{
+				if ph.Features == nil{
+					ph.Features = make(map[string]bool)
+				}
+				ph.Features[f] = true
+				continue
+			}
+			types = append(types,t)
+		}
+		ph.VCardParams.set("type",types)
+		return ph
+	case vcard.PropImpp:
+		return &OnlineService{URI:text(p),VCardName:"impp",Common:common(p)}
+	case vcard.PropAdr:
+		q := *p
+		q.Params = make(map[string][]string,len(p.Params))
+		adr := &Address{}
+		for k,vals := range p.Params{
+			if strings.EqualFold(k,"LABEL") && len(vals) > 0{
+				adr.Full = vals[0]
+				continue
+			}
+			q.Params[k] = vals
+		}
+		adr.Common = common(&q)
+		for i,kind := range addressKinds{
+			if i >= len(p.Value){
+				break
+			}
+			for _,v := range p.Value[i]{
+				if v != ""{
+					adr.Components = append(adr.Components,AddressComponent{Kind:kind,Value:v})
+				}
+			}
+		}
+		return adr
+	case vcard.PropNote:
+		return &Note{Note:text(p),Common:common(p)}
+	}
+	return nil
+}
+
+func nameComponents(p *vcard.Property) []NameComponent {
+	comps := []NameComponent{}
+	for i,kind := range nameKinds{
+		if i >= len(p.Value){
+			break
+		}
+		for _,v := range p.Value[i]{
+			if v != ""{
+				comps = append(comps,NameComponent{Kind:kind,Value:v})
+			}
+		}
+	}
+	return comps
+}
+
+/*
+map TYPE to contexts and PREF to pref,other params and the group are
+kept in VCardParams
+ */
+func common(p *vcard.Property) Common {
+	var cm Common
+	ps := make(Params)
+	if p.Group != ""{
+		ps["group"] = []string{p.Group}
+	}
+	for k,vals := range p.Params{
+		switch strings.ToUpper(k) {
+		case vcard.ParamPref:
+			if len(vals) == 1{
+				if n,err := strconv.Atoi(vals[0]);err == nil && n >= 1 && n <= 100{
+					cm.Pref = n
+					continue
+				}
+			}
+		case vcard.ParamType:
+			var types []string
+			for _,t := range vals{
+				t = strings.ToLower(t)
+				if ctx,ok := contexts[t];ok{
+					if cm.Contexts == nil{
+						cm.Contexts = make(map[string]bool)
+					}
+					cm.Contexts[ctx] = true
+					continue
+				}
+				types = append(types,t)
+			}
+			ps.set("type",types)
+			continue
+		}
+		ps[strings.ToLower(k)] = vals
+	}
+	if len(ps) > 0{
+		cm.VCardParams = ps
+	}
+	return cm
+}
+
+func (ps Params) set(k string,vals []string)  {
+	if len(vals) == 0{
+		delete(ps,k)
+		return
+	}
+	ps[k] = vals
+}
+
+/*
+the params of the property converted from the object
+ */
+func (cm *Common) property(name string,value [][]string,feats map[string]bool) *vcard.Property {
+	p := &vcard.Property{Name:name,Value:value}
+	for _,ctx := range sortedKeys(cm.Contexts){
+		t := ctx
+		for vt,c := range contexts{
+			if c == ctx{
+				t = vt
+			}
+		}
+		p.AddParam(vcard.ParamType,t)
+	}
+	for _,f := range sortedKeys(feats){
+		t := f
+		for vt,pf := range features{
+			if pf == f{
+				t = vt
+			}
+		}
+		p.AddParam(vcard.ParamType,t)
+	}
+	if cm.Pref > 0{
+		p.AddParam(vcard.ParamPref,strconv.Itoa(cm.Pref))
+	}
+	setParams(p,cm.VCardParams)
+	return p
+}
+
+func setParams(p *vcard.Property,ps Params)  {
+	for _,k := range sortedParamKeys(ps){
+		if k == "group"{
+			if len(ps[k]) > 0{
+				p.Group = ps[k][0]
+			}
+			continue
+		}
+		for _,v := range ps[k]{
+			p.AddParam(strings.ToUpper(k),v)
+		}
+	}
+}
+
+/*
+convert the JSContact card back to vCard 4.0
+ */
+func (jc *Card) ToVCard() (vcard.Card,error) {
+	c := make(vcard.Card)
+	c.Set(vcard.PropVersion,&vcard.Property{Name:vcard.PropVersion,Value:[][]string{{"4.0"}}})
+	pointers := make(map[string]*vcard.Property)
+	add := func(ptr string,p *vcard.Property) {
+		pointers[ptr] = p
+		c.Add(p.Name,p)
+	}
+	if jc.UID != ""{
+		add("uid",&vcard.Property{Name:vcard.PropUid,Value:[][]string{{jc.UID}}})
+	}
+	if jc.Kind != ""{
+		add("kind",&vcard.Property{Name:vcard.PropKind,Value:[][]string{{jc.Kind}}})
+	}
+	if n := jc.Name;n != nil{
+		if n.Full != ""{
+			p := &vcard.Property{Name:vcard.PropFN,Value:[][]string{{n.Full}}}
+			if jc.Language != ""{
+				p.SetParam(vcard.ParamLanguage,jc.Language)
+			}
+			add("name/full",p)
+		}
+		if n.Components != nil{
+			p := &vcard.Property{Name:vcard.PropN,Value:nameValue(n.Components)}
+			setParams(p,n.VCardParams)
+			add("name/components",p)
+		}
+	}
+	for _,id := range sortedIDs(jc.Nicknames){
+		add("nicknames/"+id,jc.Nicknames[id].property())
+	}
+	for _,id := range sortedIDs(jc.Organizations){
+		add("organizations/"+id,jc.Organizations[id].property())
+	}
+	for _,id := range sortedIDs(jc.Titles){
+		add("titles/"+id,jc.Titles[id].property())
+	}
+	for _,id := range sortedIDs(jc.Emails){
+		add("emails/"+id,jc.Emails[id].property())
+	}
+	for _,id := range sortedIDs(jc.Phones){
+		add("phones/"+id,jc.Phones[id].property())
+	}
+	for _,id := range sortedIDs(jc.OnlineServices){
+		add("onlineServices/"+id,jc.OnlineServices[id].property())
+	}
+	for _,id := range sortedIDs(jc.Addresses){
+		add("addresses/"+id,jc.Addresses[id].property())
+	}
+	for _,id := range sortedIDs(jc.Notes){
+		add("notes/"+id,jc.Notes[id].property())
+	}
+	if len(jc.Keywords) > 0{
+		add("keywords",&vcard.Property{Name:vcard.PropCategories,Value:[][]string{sortedKeys(jc.Keywords)}})
+	}
+
+	altIDs := 0
+	var doc interface{}
+	for _,lang := range sortedLangs(jc.Localizations){
+		patch := jc.Localizations[lang]
+		ptrs := make([]string,0,len(patch))
+		for ptr := range patch{
+			ptrs = append(ptrs,ptr)
+		}
+		sort.Strings(ptrs)
+		//the patches of a pointer into an object,e.g. titles/t1/name,
+		//are applied to a copy of the object
+		objects := make(map[string]interface{})
+		var objPtrs []string
+		for _,ptr := range ptrs{
+			if pointers[ptr] != nil{
+				continue
+			}
+			objPtr := ptr
+			for pointers[objPtr] == nil && strings.Contains(objPtr,"/"){
+				objPtr = objPtr[:strings.LastIndexByte(objPtr,'/')]
+			}
+			if pointers[objPtr] == nil{
+				return nil,fmt.Errorf("jscontact:localization %s:unknown pointer %q",lang,ptr)
+			}
+			if doc == nil{
+				b,err := json.Marshal(jc)
+				if err != nil{
+					return nil,err
+				}
+				if err := json.Unmarshal(b,&doc);err != nil{
+					return nil,err
+				}
+			}
+			obj,ok := objects[objPtr]
+			if !ok{
+				//a deep copy of the object,the patches do not modify doc
+				b,_ := json.Marshal(lookupPointer(doc,pointerSegments(objPtr)))
+				json.Unmarshal(b,&obj)
+				objects[objPtr] = obj
+				objPtrs = append(objPtrs,objPtr)
+			}
+			var v interface{}
+			if err := json.Unmarshal(patch[ptr],&v);err != nil{
+				return nil,fmt.Errorf("jscontact:localization %s:%s:%v",lang,ptr,err)
+			}
+			if !setPointer(obj,pointerSegments(ptr[len(objPtr)+1:]),v){
+				return nil,fmt.Errorf("jscontact:localization %s:unknown pointer %q",lang,ptr)
+			}
+		}
+		raws := make(map[string]json.RawMessage,len(ptrs))
+		var order []string
+		for _,ptr := range ptrs{
+			if pointers[ptr] != nil{
+				raws[ptr] = patch[ptr]
+				order = append(order,ptr)
+			}
+		}
+		for _,objPtr := range objPtrs{
+			b,err := json.Marshal(objects[objPtr])
+			if err != nil{
+				return nil,err
+			}
+			raws[objPtr] = b
+			order = append(order,objPtr)
+		}
+		sort.Strings(order)
+		for _,ptr := range order{
+			base := pointers[ptr]
+			p,err := localized(ptr,base.Name,raws[ptr])
+			if err != nil{
+				return nil,fmt.Errorf("jscontact:localization %s:%s:%v",lang,ptr,err)
+			}
+			altID := base.GetFirstParamVal(vcard.ParamAltid)
+			if altID == ""{
+				altIDs++
+				altID = strconv.Itoa(altIDs)
+				base.SetParam(vcard.ParamAltid,altID)
+			}
+			p.SetParam(vcard.ParamAltid,altID)
+			p.SetParam(vcard.ParamLanguage,lang)
+			insertAfter(c,base,p)
+		}
+	}
+
+	for i,raw := range jc.VCardProps{
+		p,err := vcard.UnmarshalJCardProperty(raw)
+		if err != nil{
+			return nil,fmt.Errorf("jscontact:vCardProps %d:%v",i,err)
+		}
+		if strings.EqualFold(p.Name,vcard.PropVersion){
+			continue
+		}
+		c.Add(p.Name,p)
+	}
+	return c,nil
+}
+
+/*
+the property of a localized value,the value is the same type as the value
+of ptr
+ */
+func localized(ptr,name string,raw json.RawMessage) (*vcard.Property,error) {
+	switch ptr {
+	case "name/full":
+		var s string
+		if err := json.Unmarshal(raw,&s);err != nil{
+			return nil,err
+		}
+		return &vcard.Property{Name:name,Value:[][]string{{s}}},nil
+	case "name/components":
+		var comps []NameComponent
+		if err := json.Unmarshal(raw,&comps);err != nil{
+			return nil,err
+		}
+		return &vcard.Property{Name:name,Value:nameValue(comps)},nil
+	}
+	var obj interface{ property() *vcard.Property }
+	switch ptr[:strings.IndexByte(ptr+"/",'/')] {
+	case "nicknames":
+		obj = &Nickname{}
+	case "organizations":
+		obj = &Organization{}
+	case "titles":
+		obj = &Title{}
+	case "emails":
+		obj = &Email{}
+	case "phones":
+		obj = &Phone{}
+	case "onlineServices":
+		obj = &OnlineService{}
+	case "addresses":
+		obj = &Address{}
+	case "notes":
+		obj = &Note{}
+	default:
+		return nil,errors.New("pointer can not be localized")
+	}
+	if err := json.Unmarshal(raw,obj);err != nil{
+		return nil,err
+	}
+	return obj.property(),nil
+}
+
+/*
+the unescaped segments of a JSON pointer,e.g. titles/t1/name
+ */
+func pointerSegments(ptr string) []string {
+	segs := strings.Split(ptr,"/")
+	for i,seg := range segs{
+		segs[i] = strings.Replace(strings.Replace(seg,"~1","/",-1),"~0","~",-1)
+	}
+	return segs
+}
+
+/*
+the value at segs in doc,nil when there is none
+ */
+func lookupPointer(doc interface{},segs []string) interface{} {
+	for _,seg := range segs{
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			doc = d[seg]
+		case []interface{}:
+			i,err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(d){
+				return nil
+			}
+			doc = d[i]
+		default:
+			return nil
+		}
+	}
+	return doc
+}
+
+/*
+set the value at segs in doc,the parent of the value has to exist
+ */
+func setPointer(doc interface{},segs []string,v interface{}) bool {
+	last := segs[len(segs)-1]
+	switch d := lookupPointer(doc,segs[:len(segs)-1]).(type) {
+	case map[string]interface{}:
+		d[last] = v
+		return true
+	case []interface{}:
+		i,err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(d){
+			return false
+		}
+		d[i] = v
+		return true
+	}
+	return false
+}
+
+/*
+insert p after base and the alternatives already inserted after it
+ */
+func insertAfter(c vcard.Card,base,p *vcard.Property)  {
+	props := c[base.Name]
+	altID := base.GetFirstParamVal(vcard.ParamAltid)
+	for i,bp := range props{
+		if bp == base{
+			for i+1 < len(props) && props[i+1].GetFirstParamVal(vcard.ParamAltid) == altID{
+				i++
+			}
+			props = append(props[:i+1],append([]*vcard.Property{p},props[i+1:]...)...)
+			break
+		}
+	}
+	c[base.Name] = props
+}
+
+func (n *Nickname) property() *vcard.Property {
+	return n.Common.property(vcard.PropNickName,[][]string{{n.Name}},nil)
+}
+
+func (o *Organization) property() *vcard.Property {
+	value := [][]string{{o.Name}}
+	for _,u := range o.Units{
+		value = append(value,[]string{u.Name})
+	}
+	return o.Common.property(vcard.PropOrg,value,nil)
+}
+
+func (t *Title) property() *vcard.Property {
+	name := vcard.PropTiTle
+	if t.Kind == "role"{
+		name = vcard.PropRole
+	}
+	return t.Common.property(name,[][]string{{t.Name}},nil)
+}
+
+func (e *Email) property() *vcard.Property {
+	return e.Common.property(vcard.PropEmail,[][]string{{e.Address}},nil)
+}
+
+func (ph *Phone) property() *vcard.Property {
+	return ph.Common.property(vcard.PropTel,[][]string{{ph.Number}},ph.Features)
+}
+
+func (s *OnlineService) property() *vcard.Property {
+	name := vcard.PropImpp
+	if s.VCardName != ""{
+		name = strings.ToUpper(s.VCardName)
+	}
+	return s.Common.property(name,[][]string{{s.URI}},nil)
+}
+
+func (a *Address) property() *vcard.Property {
+	value := make([][]string,len(addressKinds))
+	for i,kind := range addressKinds{
+		for _,comp := range a.Components{
+			if comp.Kind == kind{
+				value[i] = append(value[i],comp.Value)
+			}
+		}
+		if value[i] == nil{
+			value[i] = []string{""}
+		}
+	}
+	p := a.Common.property(vcard.PropAdr,value,nil)
+	if a.Full != ""{
+		p.SetParam("LABEL",a.Full)
+	}
+	return p
+}
+
+func (n *Note) property() *vcard.Property {
+	return n.Common.property(vcard.PropNote,[][]string{{n.Note}},nil)
+}
+
+func nameValue(comps []NameComponent) [][]string {
+	value := make([][]string,len(nameKinds))
+	for i,kind := range nameKinds{
+		for _,comp := range comps{
+			if comp.Kind == kind{
+				value[i] = append(value[i],comp.Value)
+			}
+		}
+		if value[i] == nil{
+			value[i] = []string{""}
+		}
+	}
+	return value
+}
+
+/*
+the property has no params other than names
+ */
+func onlyParams(p *vcard.Property,names ...string) bool {
+	if p.Group != ""{
+		return false
+	}
+	for k := range p.Params{
+		found := false
+		for _,n := range names{
+			found = found || strings.EqualFold(k,n)
+		}
+		if !found{
+			return false
+		}
+	}
+	return true
+}
+
+/*
+the text of a single value,a list is joined by ','
+ */
+func text(p *vcard.Property) string {
+	return strings.Join(p.GetValueTextList(),",")
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string,0,len(m))
+	for k,v := range m{
+		if v{
+			keys = append(keys,k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedParamKeys(ps Params) []string {
+	keys := make([]string,0,len(ps))
+	for k := range ps{
+		keys = append(keys,k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedLangs(m map[string]map[string]json.RawMessage) []string {
+	keys := make([]string,0,len(m))
+	for k := range m{
+		keys = append(keys,k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+ids of a collection,the generated ids like e2 are before e10
+ */
+func sortedIDs(m interface{}) []string {
+	var ids []string
+	switch m := m.(type) {
+	case map[string]*Nickname:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*Organization:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*Title:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*Email:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*Phone:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*OnlineService:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*Address:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	case map[string]*Note:
+		for id := range m{
+			ids = append(ids,id)
+		}
+	}
+	sort.Slice(ids,func(i,j int) bool {
+		if len(ids[i]) != len(ids[j]){
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
@@ -0,0 +1,201 @@
+package jscontact
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	vcard "github.com/ScottAI/go-vcard"
+)
+
+var testCard = vcard.Card{
+	vcard.PropVersion: {{Name: vcard.PropVersion, Value: [][]string{{"4.0"}}}},
+	vcard.PropFN: {
+		{Name: vcard.PropFN, Params: map[string][]string{vcard.ParamAltid: {"1"}}, Value: [][]string{{"Jean Dupont"}}},
+		{Name: vcard.PropFN, Params: map[string][]string{vcard.ParamAltid: {"1"}, vcard.ParamLanguage: {"ja"}}, Value: [][]string{{"ジャン・デュポン"}}},
+	},
+	vcard.PropN: {{Name: vcard.PropN, Value: [][]string{{"Dupont"}, {"Jean"}, {""}, {"Dr."}, {""}}}},
+	vcard.PropEmail: {{
+		Name:   vcard.PropEmail,
+		Params: map[string][]string{vcard.ParamType: {"work"}, vcard.ParamPref: {"1"}},
+		Value:  [][]string{{"jean@example.com"}},
+	}},
+	vcard.PropTel: {{
+		Group:  "item1",
+		Name:   vcard.PropTel,
+		Params: map[string][]string{vcard.ParamType: {"home", "cell"}, vcard.ParamValue: {"uri"}},
+		Value:  [][]string{{"tel:+33-1-23-45-67-89"}},
+	}},
+	vcard.PropAdr: {{
+		Name:   vcard.PropAdr,
+		Params: map[string][]string{vcard.ParamType: {"home"}, "LABEL": {"1 rue de la Paix\nParis"}},
+		Value:  [][]string{{""}, {""}, {"1 rue de la Paix"}, {"Paris"}, {""}, {"75002"}, {"France"}},
+	}},
+	vcard.PropTiTle: {
+		{Name: vcard.PropTiTle, Params: map[string][]string{vcard.ParamAltid: {"t"}}, Value: [][]string{{"Engineer"}}},
+		{Name: vcard.PropTiTle, Params: map[string][]string{vcard.ParamAltid: {"t"}, vcard.ParamLanguage: {"fr"}}, Value: [][]string{{"Ingénieur"}}},
+	},
+	vcard.PropImpp: {{Name: vcard.PropImpp, Value: [][]string{{"xmpp:jean@example.com"}}}},
+	"X-FOO":        {{Name: "X-FOO", Value: [][]string{{"bar"}}}},
+}
+
+func TestFromVCard(t *testing.T) {
+	jc, err := FromVCard(testCard)
+	if err != nil {
+		t.Fatal("Expected no error when converting to JSContact, got:", err)
+	}
+
+	if jc.Name == nil || jc.Name.Full != "Jean Dupont" {
+		t.Fatalf("Expected name.full from FN but got %+v", jc.Name)
+	}
+	comps := []NameComponent{{"surname", "Dupont"}, {"given", "Jean"}, {"title", "Dr."}}
+	if !reflect.DeepEqual(jc.Name.Components, comps) {
+		t.Errorf("Expected name components %v but got %v", comps, jc.Name.Components)
+	}
+	if e := jc.Emails["e1"]; e == nil || e.Address != "jean@example.com" || !e.Contexts["work"] || e.Pref != 1 {
+		t.Errorf("Expected work email with pref 1 but got %+v", e)
+	}
+	ph := jc.Phones["p1"]
+	if ph == nil || !ph.Contexts["private"] || !ph.Features["mobile"] {
+		t.Fatalf("Expected private mobile phone but got %+v", ph)
+	}
+	if g := ph.VCardParams["group"]; len(g) != 1 || g[0] != "item1" {
+		t.Errorf("Expected group in vCardParams but got %v", ph.VCardParams)
+	}
+	adr := jc.Addresses["a1"]
+	if adr == nil || adr.Full != "1 rue de la Paix\nParis" || len(adr.Components) != 4 {
+		t.Errorf("Expected address with label and 4 components but got %+v", adr)
+	}
+	if s := jc.OnlineServices["s1"]; s == nil || s.URI != "xmpp:jean@example.com" {
+		t.Errorf("Expected online service from IMPP but got %+v", s)
+	}
+	if v := string(jc.Localizations["ja"]["name/full"]); v != `"ジャン・デュポン"` {
+		t.Errorf("Expected localized name.full but got %s", v)
+	}
+	if v := string(jc.Localizations["fr"]["titles/t1"]); v != `{"name":"Ingénieur","kind":"title"}` {
+		t.Errorf("Expected localized title but got %s", v)
+	}
+	if len(jc.VCardProps) != 1 || string(jc.VCardProps[0]) != `["x-foo",{},"unknown","bar"]` {
+		t.Errorf("Expected X-FOO in vCardProps but got %s", jc.VCardProps)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	jc, err := FromVCard(testCard)
+	if err != nil {
+		t.Fatal("Expected no error when converting to JSContact, got:", err)
+	}
+	data, err := json.Marshal(jc)
+	if err != nil {
+		t.Fatal("Expected no error when marshaling JSContact, got:", err)
+	}
+	var decoded Card
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal("Expected no error when unmarshaling JSContact, got:", err)
+	}
+	card, err := decoded.ToVCard()
+	if err != nil {
+		t.Fatal("Expected no error when converting to vCard, got:", err)
+	}
+	for k, props := range testCard {
+		if !reflect.DeepEqual(card[k], props) {
+			t.Errorf("Expected %s to round-trip\n%+v\nbut got\n%+v", k, derefs(props), derefs(card[k]))
+		}
+	}
+	if len(card) != len(testCard) {
+		t.Errorf("Expected %d properties but got %d", len(testCard), len(card))
+	}
+}
+
+func derefs(props []*vcard.Property) []vcard.Property {
+	var ps []vcard.Property
+	for _, p := range props {
+		ps = append(ps, *p)
+	}
+	return ps
+}
+
+func TestFromVCard_language(t *testing.T) {
+	card := vcard.Card{
+		vcard.PropVersion: {{Name: vcard.PropVersion, Value: [][]string{{"4.0"}}}},
+		vcard.PropFN: {
+			{Name: vcard.PropFN, Params: map[string][]string{vcard.ParamAltid: {"1"}, vcard.ParamLanguage: {"en"}}, Value: [][]string{{"John Smith"}}},
+			{Name: vcard.PropFN, Params: map[string][]string{vcard.ParamAltid: {"1"}, vcard.ParamLanguage: {"ja"}}, Value: [][]string{{"ジョン・スミス"}}},
+			{Name: vcard.PropFN, Params: map[string][]string{vcard.ParamAltid: {"1"}, vcard.ParamLanguage: {"fr"}}, Value: [][]string{{"Jean Smith"}}},
+		},
+	}
+
+	jc, err := FromVCard(card)
+	if err != nil {
+		t.Fatal("Expected no error when converting to JSContact, got:", err)
+	}
+	if jc.Name == nil || jc.Name.Full != "John Smith" {
+		t.Fatalf("Expected name.full from the first FN but got %+v", jc.Name)
+	}
+	if jc.Language != "en" {
+		t.Errorf("Expected card language en but got %q", jc.Language)
+	}
+	if v := string(jc.Localizations["ja"]["name/full"]); v != `"ジョン・スミス"` {
+		t.Errorf("Expected ja localization of name.full but got %s", v)
+	}
+	if v := string(jc.Localizations["fr"]["name/full"]); v != `"Jean Smith"` {
+		t.Errorf("Expected fr localization of name.full but got %s", v)
+	}
+	if len(jc.VCardProps) != 0 {
+		t.Errorf("Expected no vCardProps but got %s", jc.VCardProps)
+	}
+
+	back, err := jc.ToVCard()
+	if err != nil {
+		t.Fatal("Expected no error when converting to vCard, got:", err)
+	}
+	fns := back[vcard.PropFN]
+	if len(fns) != 3 {
+		t.Fatalf("Expected 3 FN but got %+v", derefs(fns))
+	}
+	for i, lang := range []string{"en", "fr", "ja"} {
+		if v := fns[i].GetFirstParamVal(vcard.ParamLanguage); v != lang {
+			t.Errorf("Expected FN %d in %s but got %q", i, lang, v)
+		}
+		if v := fns[i].GetFirstParamVal(vcard.ParamAltid); v == "" || v != fns[0].GetFirstParamVal(vcard.ParamAltid) {
+			t.Errorf("Expected FN %d in the ALTID of the first FN but got %q", i, v)
+		}
+	}
+}
+
+func TestToVCard_localizedProperty(t *testing.T) {
+	data := `{"@type":"Card","version":"1.0","uid":"urn:uuid:1",` +
+		`"name":{"full":"John Smith","components":[{"kind":"given","value":"John"},{"kind":"surname","value":"Smith"}]},` +
+		`"titles":{"t1":{"name":"Engineer","kind":"title"}},` +
+		`"localizations":{"fr":{"titles/t1/name":"Ingénieur","name/components/0/value":"Jean"}}}`
+	var jc Card
+	if err := json.Unmarshal([]byte(data), &jc); err != nil {
+		t.Fatal("Expected no error when unmarshaling JSContact, got:", err)
+	}
+	card, err := jc.ToVCard()
+	if err != nil {
+		t.Fatal("Expected no error when converting to vCard, got:", err)
+	}
+
+	titles := card[vcard.PropTiTle]
+	if len(titles) != 2 || titles[1].GetValueFirstText() != "Ingénieur" || titles[1].GetFirstParamVal(vcard.ParamLanguage) != "fr" {
+		t.Fatalf("Expected the fr TITLE after the TITLE but got %+v", derefs(titles))
+	}
+	if v := titles[1].GetFirstParamVal(vcard.ParamAltid); v == "" || v != titles[0].GetFirstParamVal(vcard.ParamAltid) {
+		t.Errorf("Expected the fr TITLE in the ALTID of the TITLE but got %q", v)
+	}
+	ns := card[vcard.PropN]
+	n := [][]string{{"Smith"}, {"Jean"}, {""}, {""}, {""}}
+	if len(ns) != 2 || !reflect.DeepEqual(ns[1].Value, n) {
+		t.Fatalf("Expected the fr N %q but got %+v", n, derefs(ns))
+	}
+	if jc.Titles["t1"].Name != "Engineer" || jc.Name.Components[0].Value != "John" {
+		t.Errorf("Expected the localizations not to modify the card but got %+v", jc)
+	}
+
+	jc.Localizations["fr"]["titles/t2/name"] = json.RawMessage(`"Directeur"`)
+	if _, err := jc.ToVCard(); err == nil || !strings.HasPrefix(err.Error(), "jscontact:") {
+		t.Errorf("Expected an error for a pointer to an unknown title but got %v", err)
+	}
+}
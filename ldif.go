@@ -0,0 +1,557 @@
+package go_vcard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//https://tools.ietf.org/html/rfc2849
+//LDIF,and the mapping of the inetOrgPerson schema (RFC 2798) to vCard
+
+/*
+an LDIF entry,attributes are in their order in the LDIF
+ */
+type LDIFEntry struct {
+	DN string
+	Attributes []LDIFAttribute
+}
+
+type LDIFAttribute struct {
+	Name string
+	Value string //binary for e.g. jpegPhoto
+	URL bool //Value is the URL of the value,written as "name:< url"
+}
+
+/*
+values of an attribute,the name is case-insensitive and its options,
+e.g. ;lang-en,are ignored
+ */
+func (e *LDIFEntry) Get(name string) []string {
+	var vals []string
+	for _,a := range e.Attributes{
+		if strings.EqualFold(ldifBaseName(a.Name),name) && !a.URL{
+			vals = append(vals,a.Value)
+		}
+	}
+	return vals
+}
+
+func (e *LDIFEntry) Add(name,value string)  {
+	e.Attributes = append(e.Attributes,LDIFAttribute{Name:name,Value:value})
+}
+
+func ldifBaseName(name string) string {
+	if i := strings.IndexByte(name,';');i >= 0{
+		return name[:i]
+	}
+	return name
+}
+
+/*
+LDIFReader read the entries of an LDIF stream
+ */
+type LDIFReader struct {
+	r *bufio.Reader
+	lineNo int
+	next string //read ahead line
+	hasNext bool
+	started bool
+}
+
+func NewLDIFReader(r io.Reader) *LDIFReader {
+	return &LDIFReader{r:bufio.NewReader(r)}
+}
+
+/*
+read the next entry,return io.EOF when there is no more entry.change
+records other than changetype add are not supported
+ */
+func (lr *LDIFReader) Read() (*LDIFEntry,error) {
+	var e *LDIFEntry
+	for{
+		line,lineNo,err := lr.readLine()
+		if err == io.EOF && e != nil{
+			return e,nil
+		}
+		if err != nil{
+			return nil,err
+		}
+		if line == ""{
+			if e != nil{
+				return e,nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line,"#"){
+			continue
+		}
+		a,err := parseLDIFLine(line)
+		if err != nil{
+			return nil,&ParseError{Line:lineNo,Raw:line,Reason:err.Error()}
+		}
+		if !lr.started{
+			lr.started = true
+			if strings.EqualFold(a.Name,"version"){
+				continue
+			}
+		}
+		if e == nil{
+			if !strings.EqualFold(a.Name,"dn"){
+				return nil,&ParseError{Line:lineNo,Raw:line,Reason:"entry does not start with dn"}
+			}
+			e = &LDIFEntry{DN:a.Value}
+			continue
+		}
+		if strings.EqualFold(a.Name,"changetype"){
+			if !strings.EqualFold(a.Value,"add"){
+				return nil,&ParseError{Line:lineNo,Raw:line,Reason:"unsupported changetype "+a.Value}
+			}
+			continue
+		}
+		e.Attributes = append(e.Attributes,a)
+	}
+}
+
+/*
+read an unfolded line,a line starting with a space continue the previous one
+ */
+func (lr *LDIFReader) readLine() (string,int,error) {
+	line,err := lr.readPhysical()
+	if err != nil{
+		return "",0,err
+	}
+	lineNo := lr.lineNo
+	//e.g. a jpegPhoto:: has many continuation lines,they are appended to b
+	//to unfold in linear time
+	var b strings.Builder
+	for line != ""{
+		next,err := lr.readPhysical()
+		if err == io.EOF{
+			break
+		}
+		if err != nil{
+			return "",0,err
+		}
+		if !strings.HasPrefix(next," "){
+			lr.next,lr.hasNext = next,true
+			lr.lineNo--
+			break
+		}
+		if b.Len() == 0{
+			b.WriteString(line)
+		}
+		b.WriteString(next[1:])
+	}
+	if b.Len() > 0{
+		line = b.String()
+	}
+	return line,lineNo,nil
+}
+
+func (lr *LDIFReader) readPhysical() (string,error) {
+	lr.lineNo++
+	if lr.hasNext{
+		lr.hasNext = false
+		return lr.next,nil
+	}
+	line,err := lr.r.ReadString('\n')
+	if err == io.EOF && line != ""{
+		err = nil
+	}
+	if err != nil{
+		lr.lineNo--
+		return "",err
+	}
+	return strings.TrimRight(line,"\r\n"),nil
+}
+
+func parseLDIFLine(line string) (LDIFAttribute,error) {
+	i := strings.IndexByte(line,':')
+	if i <= 0{
+		return LDIFAttribute{},errors.New("missing ':'")
+	}
+	a := LDIFAttribute{Name:line[:i]}
+	v := line[i+1:]
+	switch {
+	case strings.HasPrefix(v,":"):
+		data,err := base64.StdEncoding.DecodeString(strings.TrimSpace(v[1:]))
+		if err != nil{
+			return a,fmt.Errorf("invalid base64 value:%v",err)
+		}
+		a.Value = string(data)
+	case strings.HasPrefix(v,"<"):
+		a.Value = strings.TrimSpace(v[1:])
+		a.URL = true
+	default:
+		a.Value = strings.TrimLeft(v," ")
+	}
+	return a,nil
+}
+
+/*
+LDIFWriter write entries as LDIF,the version line is written before the
+first entry
+ */
+type LDIFWriter struct {
+	w io.Writer
+	started bool
+}
+
+func NewLDIFWriter(w io.Writer) *LDIFWriter {
+	return &LDIFWriter{w:w}
+}
+
+/*
+write an entry with a single Write
+ */
+func (lw *LDIFWriter) Write(e *LDIFEntry) error {
+	var buf bytes.Buffer
+	if !lw.started{
+		buf.WriteString("version: 1\n")
+	}
+	buf.WriteString("\n")
+	writeLDIFLine(&buf,LDIFAttribute{Name:"dn",Value:e.DN})
+	for _,a := range e.Attributes{
+		writeLDIFLine(&buf,a)
+	}
+	if _,err := lw.w.Write(buf.Bytes());err != nil{
+		return err
+	}
+	lw.started = true
+	return nil
+}
+
+//max length of an LDIF line
+const maxLDIFLine = 76
+
+func writeLDIFLine(buf *bytes.Buffer,a LDIFAttribute)  {
+	var line string
+	switch {
+	case a.URL:
+		line = a.Name+":< "+a.Value
+	case ldifSafe(a.Value):
+		line = a.Name+": "+a.Value
+	default:
+		line = a.Name+":: "+base64.StdEncoding.EncodeToString([]byte(a.Value))
+	}
+	max := maxLDIFLine
+	for len(line) > max{
+		buf.WriteString(line[:max])
+		buf.WriteString("\n ")
+		line = line[max:]
+		max = maxLDIFLine-1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\n")
+}
+
+/*
+the value can be written as it is (SAFE-STRING of RFC 2849)
+ */
+func ldifSafe(v string) bool {
+	if v == ""{
+		return true
+	}
+	if c := v[0];c == ' ' || c == ':' || c == '<' || v[len(v)-1] == ' '{
+		return false
+	}
+	for i := 0;i < len(v);i++{
+		if c := v[i];c == 0 || c == '\r' || c == '\n' || c >= 0x80{
+			return false
+		}
+	}
+	return true
+}
+
+//object classes of the entries written by CardToLDIF
+var ldifObjectClasses = []string{"top","person","organizationalPerson","inetOrgPerson"}
+
+/*
+convert a card to an inetOrgPerson entry,the DN is cn=FN under baseDN.
+properties without an inetOrgPerson attribute are not converted
+ */
+func CardToLDIF(c Card,baseDN string) (*LDIFEntry,error) {
+	cn := ""
+	if fn := c.Get(PropFN);fn != nil{
+		cn = fn.GetValueFirstText()
+	}
+	if cn == ""{
+		cn = csvFormattedName(c)
+	}
+	if cn == ""{
+		return nil,errors.New("vcard:ldif:card has no name for cn")
+	}
+	e := &LDIFEntry{DN:"cn="+escapeDN(cn)}
+	if baseDN != ""{
+		e.DN += ","+baseDN
+	}
+	for _,oc := range ldifObjectClasses{
+		e.Add("objectClass",oc)
+	}
+	e.Add("cn",cn)
+	sn,given := "",""
+	if n := c.Name();n != nil{
+		sn,given = n.FamilyName,n.GivenName
+	}
+	if sn == ""{
+		//sn is required by person
+		sn = cn
+	}
+	e.Add("sn",sn)
+	if given != ""{
+		e.Add("givenName",given)
+	}
+	if org := c.Get(PropOrg);org != nil{
+		if o := component(org.Value,0);o != ""{
+			e.Add("o",o)
+		}
+		if ou := component(org.Value,1);ou != ""{
+			e.Add("ou",ou)
+		}
+	}
+	for _,p := range c[PropTiTle]{
+		e.Add("title",p.GetValueFirstText())
+	}
+	for _,p := range c[PropEmail]{
+		e.Add("mail",p.GetValueFirstText())
+	}
+	for _,p := range c[PropTel]{
+		e.Add(telAttribute(p),strings.TrimPrefix(joinValue(p.Value),"tel:"))
+	}
+	work := false
+	for _,p := range c[PropAdr]{
+		a := newAddress(p)
+		lines := addressLines(a)
+		if len(lines) == 0{
+			continue
+		}
+		if p.IsHasType("home"){
+			e.Add("homePostalAddress",postalAddress(lines))
+			continue
+		}
+		if work{
+			continue
+		}
+		//the components of the first work address are attributes too
+		work = true
+		e.Add("postalAddress",postalAddress(lines))
+		for _,av := range [][2]string{
+			{"postOfficeBox",a.PostOfficeBox},{"street",a.StreetAddress},{"l",a.Locality},
+			{"st",a.Region},{"postalCode",a.PostalCode},
+		}{
+			if av[1] != ""{
+				e.Add(av[0],av[1])
+			}
+		}
+	}
+	for _,p := range c[PropUrl]{
+		e.Add("labeledURI",joinValue(p.Value))
+	}
+	for _,p := range c[PropNote]{
+		e.Add("description",joinValue(p.Value))
+	}
+	for _,p := range c[PropPhoto]{
+		q := *p
+		q.Params = copyParams(p.Params)
+		v4Media(&q)
+		uri := joinValue(q.Value)
+		const jpeg = "data:image/jpeg;base64,"
+		if len(uri) > len(jpeg) && strings.EqualFold(uri[:len(jpeg)],jpeg){
+			data,err := base64.StdEncoding.DecodeString(uri[len(jpeg):])
+			if err != nil{
+				return nil,fmt.Errorf("vcard:ldif:PHOTO:%v",err)
+			}
+			e.Add("jpegPhoto",string(data))
+		}else if !strings.HasPrefix(strings.ToLower(uri),"data:"){
+			e.Attributes = append(e.Attributes,LDIFAttribute{Name:"jpegPhoto",Value:uri,URL:true})
+		}
+	}
+	return e,nil
+}
+
+/*
+attribute of a TEL by its types
+ */
+func telAttribute(p *Property) string {
+	switch {
+	case p.IsHasType("cell"):
+		return "mobile"
+	case p.IsHasType("pager"):
+		return "pager"
+	case p.IsHasType("fax"):
+		return "facsimileTelephoneNumber"
+	case p.IsHasType("home"):
+		return "homePhone"
+	}
+	return "telephoneNumber"
+}
+
+//TEL types of the phone attributes
+var ldifTelTypes = map[string][]string{
+	"telephonenumber":{"work","voice"},
+	"homephone":{"home","voice"},
+	"mobile":{"cell"},
+	"facsimiletelephonenumber":{"fax"},
+	"pager":{"pager"},
+}
+
+/*
+convert an inetOrgPerson entry to a vCard 4.0 card
+ */
+func LDIFToCard(e *LDIFEntry) (Card,error) {
+	c := make(Card)
+	c.Set(PropVersion,&Property{Name:PropVersion,Value:[][]string{{"4.0"}}})
+	add := func(name string,value [][]string,types ...string) *Property {
+		p := &Property{Name:name,Value:value}
+		for _,t := range types{
+			p.AddParam(ParamType,t)
+		}
+		c.Add(name,p)
+		return p
+	}
+	cns := e.Get("cn")
+	if len(cns) == 0{
+		return nil,fmt.Errorf("vcard:ldif:%s:no cn",e.DN)
+	}
+	add(PropFN,[][]string{{cns[0]}})
+	sn,given := first(e.Get("sn")),first(e.Get("givenName"))
+	if sn != "" || given != ""{
+		add(PropN,[][]string{{sn},{given},{""},{""},{""}})
+	}
+	if o,ou := first(e.Get("o")),first(e.Get("ou"));o != "" || ou != ""{
+		value := [][]string{{o}}
+		if ou != ""{
+			value = append(value,[]string{ou})
+		}
+		add(PropOrg,value)
+	}
+	for _,a := range e.Attributes{
+		name := strings.ToLower(ldifBaseName(a.Name))
+		switch name {
+		case "title":
+			add(PropTiTle,[][]string{{a.Value}})
+		case "mail":
+			add(PropEmail,[][]string{{a.Value}})
+		case "telephonenumber","homephone","mobile","facsimiletelephonenumber","pager":
+			add(PropTel,[][]string{{a.Value}},ldifTelTypes[name]...)
+		case "homepostaladdress":
+			lines := parsePostalAddress(a.Value)
+			p := add(PropAdr,[][]string{{""},{""},{strings.Join(lines,"\n")},{""},{""},{""},{""}},"home")
+			p.SetParam(paramLabel,strings.Join(lines,"\n"))
+		case "labeleduri":
+			//labeledURI may be followed by a label
+			uri := strings.Fields(a.Value)
+			if len(uri) > 0{
+				add(PropUrl,[][]string{{uri[0]}})
+			}
+		case "description":
+			add(PropNote,[][]string{{a.Value}})
+		case "jpegphoto":
+			if a.URL{
+				add(PropPhoto,parseValues(a.Value))
+			}else{
+				add(PropPhoto,parseValues("data:image/jpeg;base64,"+base64.StdEncoding.EncodeToString([]byte(a.Value))))
+			}
+		}
+	}
+	//the work address is made of postalAddress and the address attributes
+	adr := &Address{
+		PostOfficeBox:first(e.Get("postOfficeBox")),
+		StreetAddress:first(e.Get("street")),
+		Locality:first(e.Get("l")),
+		Region:first(e.Get("st")),
+		PostalCode:first(e.Get("postalCode")),
+	}
+	pa := e.Get("postalAddress")
+	if len(pa) > 0 || *adr != (Address{}){
+		var label string
+		if len(pa) > 0{
+			label = strings.Join(parsePostalAddress(pa[0]),"\n")
+			if *adr == (Address{}){
+				adr.StreetAddress = label
+			}
+		}
+		p := adr.property()
+		p.Name = PropAdr
+		p.AddParam(ParamType,"work")
+		if label != ""{
+			p.SetParam(paramLabel,label)
+		}
+		//the work address is the first one
+		c[PropAdr] = append([]*Property{p},c[PropAdr]...)
+	}
+	return c,nil
+}
+
+func first(vals []string) string {
+	if len(vals) == 0{
+		return ""
+	}
+	return vals[0]
+}
+
+/*
+lines of an address,the LABEL param or lines made of the components
+ */
+func addressLines(a *Address) []string {
+	if label := a.GetFirstParamVal(paramLabel);label != ""{
+		return strings.Split(label,"\n")
+	}
+	city := strings.TrimSpace(strings.Join(nonEmpty(a.Locality,a.Region)," ")+" "+a.PostalCode)
+	return nonEmpty(a.ExtendedAddress,a.StreetAddress,a.PostOfficeBox,city,a.Country)
+}
+
+func nonEmpty(vals ...string) []string {
+	var ne []string
+	for _,v := range vals{
+		if v != ""{
+			ne = append(ne,v)
+		}
+	}
+	return ne
+}
+
+var postalEscaper = strings.NewReplacer(`\`,`\5C`,"$",`\24`)
+var postalUnescaper = strings.NewReplacer(`\5C`,`\`,`\5c`,`\`,`\24`,"$")
+
+/*
+postalAddress syntax:lines separated by '$' (RFC 4517 3.3.28)
+ */
+func postalAddress(lines []string) string {
+	esc := make([]string,len(lines))
+	for i,l := range lines{
+		esc[i] = postalEscaper.Replace(l)
+	}
+	return strings.Join(esc,"$")
+}
+
+func parsePostalAddress(v string) []string {
+	lines := strings.Split(v,"$")
+	for i,l := range lines{
+		lines[i] = postalUnescaper.Replace(strings.TrimSpace(l))
+	}
+	return lines
+}
+
+/*
+escape a DN attribute value (RFC 4514 2.4)
+ */
+func escapeDN(v string) string {
+	var b strings.Builder
+	for i := 0;i < len(v);i++{
+		c := v[i]
+		switch {
+		case strings.IndexByte(`,+"\<>;=`,c) >= 0,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(v)-1):
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
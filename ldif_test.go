@@ -0,0 +1,189 @@
+package go_vcard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLDIF = "version: 1\n" +
+	"\n" +
+	"# Joe\n" +
+	"dn: cn=Joe Bloggs,ou=People,dc=example,dc=com\n" +
+	"objectClass: inetOrgPerson\n" +
+	"cn: Joe Bloggs\n" +
+	"sn: Bloggs\n" +
+	"givenName: Joe\n" +
+	"o: ABC Inc.\n" +
+	"mail: joe@example.com\n" +
+	"telephoneNumber: +1 555 0100\n" +
+	"mobile: +1 555 0102\n" +
+	"postalAddress: 1 Main St$Springfield IL 12345\n" +
+	"street: 1 Main St\n" +
+	"l: Springfield\n" +
+	"description:: TXVsbGVyIGFuZCBTw7ZobmU=\n" +
+	"jpegPhoto:: /9j/4AAQ\n" +
+	"labeledURI: http://example.com/jo\n" +
+	" e Home page\n" +
+	"\n" +
+	"dn: cn=Jane,dc=example,dc=com\n" +
+	"changetype: add\n" +
+	"cn: Jane\n"
+
+func TestLDIFReader(t *testing.T) {
+	r := NewLDIFReader(strings.NewReader(testLDIF))
+	e, err := r.Read()
+	if err != nil {
+		t.Fatal("Expected no error when reading LDIF, got:", err)
+	}
+	if e.DN != "cn=Joe Bloggs,ou=People,dc=example,dc=com" {
+		t.Errorf("Expected DN of Joe but got %q", e.DN)
+	}
+	if v := e.Get("description"); len(v) != 1 || v[0] != "Muller and Söhne" {
+		t.Errorf("Expected base64 description to be decoded but got %q", v)
+	}
+	if v := e.Get("labeledURI"); len(v) != 1 || v[0] != "http://example.com/joe Home page" {
+		t.Errorf("Expected folded labeledURI to be unfolded but got %q", v)
+	}
+
+	e, err = r.Read()
+	if err != nil {
+		t.Fatal("Expected no error when reading second entry, got:", err)
+	}
+	if v := e.Get("cn"); len(v) != 1 || v[0] != "Jane" {
+		t.Errorf("Expected cn Jane but got %q", v)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of LDIF but got %v", err)
+	}
+
+	if _, err := NewLDIFReader(strings.NewReader("cn: Joe\n")).Read(); err == nil {
+		t.Error("Expected an error for an entry without dn")
+	}
+}
+
+func TestLDIFReader_largeFoldedValue(t *testing.T) {
+	//about 4 MB of base64
+	photo := bytes.Repeat([]byte("\xff\xd8\xff\xe0"), 750000)
+	b64 := base64.StdEncoding.EncodeToString(photo)
+	var b strings.Builder
+	b.WriteString("dn: cn=Joe\ncn: Joe\njpegPhoto:: ")
+	for i := 0; i < len(b64); i += 76 {
+		if i > 0 {
+			b.WriteString("\n ")
+		}
+		end := i + 76
+		if end > len(b64) {
+			end = len(b64)
+		}
+		b.WriteString(b64[i:end])
+	}
+	b.WriteString("\n")
+
+	type result struct {
+		e   *LDIFEntry
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		e, err := NewLDIFReader(strings.NewReader(b.String())).Read()
+		done <- result{e, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal("Expected no error when reading a large jpegPhoto, got:", r.err)
+		}
+		if v := r.e.Get("jpegPhoto"); len(v) != 1 || v[0] != string(photo) {
+			t.Errorf("Expected the jpegPhoto to be unfolded and decoded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a large folded value to be unfolded in linear time")
+	}
+}
+
+func TestLDIFToCard(t *testing.T) {
+	e, err := NewLDIFReader(strings.NewReader(testLDIF)).Read()
+	if err != nil {
+		t.Fatal("Expected no error when reading LDIF, got:", err)
+	}
+	card, err := LDIFToCard(e)
+	if err != nil {
+		t.Fatal("Expected no error when converting LDIF, got:", err)
+	}
+
+	if v := card.Get(PropFN).GetValueFirstText(); v != "Joe Bloggs" {
+		t.Errorf("Expected FN Joe Bloggs but got %q", v)
+	}
+	if n := card.Name(); n.FamilyName != "Bloggs" || n.GivenName != "Joe" {
+		t.Errorf("Expected N Bloggs;Joe but got %+v", n)
+	}
+	tels := card[PropTel]
+	if len(tels) != 2 || !tels[0].IsHasType("work") || !tels[1].IsHasType("cell") {
+		t.Errorf("Expected work and cell TEL but got %v", tels)
+	}
+	adr := card.Address()
+	if adr.StreetAddress != "1 Main St" || adr.Locality != "Springfield" || adr.GetFirstParamVal(paramLabel) != "1 Main St\nSpringfield IL 12345" {
+		t.Errorf("Expected work ADR from address attributes but got %+v", adr)
+	}
+	if v := joinValue(card.Get(PropPhoto).Value); v != "data:image/jpeg;base64,/9j/4AAQ" {
+		t.Errorf("Expected jpegPhoto as data: URI but got %q", v)
+	}
+	if v := card.Get(PropUrl).GetValueFirstText(); v != "http://example.com/joe" {
+		t.Errorf("Expected URL without label but got %q", v)
+	}
+}
+
+func TestCardToLDIF(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"Bloggs, Joe"}}}},
+		PropN:       {{Name: PropN, Value: [][]string{{"Bloggs"}, {"Joe"}, {""}, {""}, {""}}}},
+		PropTel: {
+			{Name: PropTel, Params: map[string][]string{ParamType: {"home", "voice"}}, Value: [][]string{{"tel:+1-555-0100"}}},
+			{Name: PropTel, Params: map[string][]string{ParamType: {"cell"}}, Value: [][]string{{"+1 555 0102"}}},
+		},
+		PropAdr: {{Name: PropAdr, Value: [][]string{{""}, {""}, {"1 Main St"}, {"Springfield"}, {"IL"}, {"12345"}, {""}}}},
+		PropNote:  {{Name: PropNote, Value: [][]string{{"Ünïcode"}}}},
+		PropPhoto: {{Name: PropPhoto, Value: parseValues("data:image/jpeg;base64,/9j/4AAQ")}},
+	}
+
+	e, err := CardToLDIF(card, "ou=People,dc=example,dc=com")
+	if err != nil {
+		t.Fatal("Expected no error when converting card to LDIF, got:", err)
+	}
+	var b bytes.Buffer
+	if err := NewLDIFWriter(&b).Write(e); err != nil {
+		t.Fatal("Expected no error when writing LDIF, got:", err)
+	}
+	out := b.String()
+	expected := []string{
+		"version: 1\n",
+		"dn: cn=Bloggs\\, Joe,ou=People,dc=example,dc=com\n",
+		"objectClass: inetOrgPerson\n",
+		"sn: Bloggs\n",
+		"givenName: Joe\n",
+		"homePhone: +1-555-0100\n",
+		"mobile: +1 555 0102\n",
+		"postalAddress: 1 Main St$Springfield IL 12345\n",
+		"l: Springfield\n",
+		"description:: w5xuw69jb2Rl\n",
+		"jpegPhoto:: /9j/4AAQ\n",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected LDIF to contain %q but got:\n%s", s, out)
+		}
+	}
+
+	got, err := NewLDIFReader(&b).Read()
+	if err != nil {
+		t.Fatal("Expected no error when reading written LDIF, got:", err)
+	}
+	if got.DN != e.DN || len(got.Attributes) != len(e.Attributes) {
+		t.Errorf("Expected written entry to be read back but got %+v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package go_vcard
+
+import (
+	"errors"
+	"strings"
+)
+
+//MeCard,the compact contact format of QR codes,e.g.
+//MECARD:N:Bloggs,Joe;TEL:+15550100;EMAIL:joe@example.com;;
+
+const meCardPrefix = "MECARD:"
+
+var meCardEscaper = strings.NewReplacer(`\`,`\\`,";",`\;`,",",`\,`,":",`\:`)
+
+/*
+encode a card as a MECARD string.N,TEL,EMAIL,ADR,URL,NOTE,BDAY,NICKNAME
+and ORG are written,other properties have no MeCard field
+ */
+func MarshalMeCard(c Card) (string,error) {
+	var b strings.Builder
+	b.WriteString(meCardPrefix)
+	field := func(name,value string) {
+		if value == ""{
+			return
+		}
+		b.WriteString(name)
+		b.WriteString(":")
+		b.WriteString(value)
+		b.WriteString(";")
+	}
+	esc := meCardEscaper.Replace
+	if n := c.Name();n != nil && (n.FamilyName != "" || n.GivenName != ""){
+		field("N",esc(n.FamilyName)+","+esc(n.GivenName))
+	}else if fn := c.Get(PropFN);fn != nil && fn.GetValueFirstText() != ""{
+		field("N",esc(joinValue(fn.Value)))
+	}else{
+		return "",errors.New("vcard:mecard:card has no name")
+	}
+	for _,p := range c[PropTel]{
+		name := "TEL"
+		if p.IsHasType("video"){
+			name = "TEL-AV"
+		}
+		field(name,esc(strings.TrimPrefix(joinValue(p.Value),"tel:")))
+	}
+	for _,p := range c[PropEmail]{
+		field("EMAIL",esc(joinValue(p.Value)))
+	}
+	for _,p := range c[PropNote]{
+		field("NOTE",esc(joinValue(p.Value)))
+	}
+	if p := c.Get(PropBday);p != nil{
+		//MeCard birthday is 8 digits
+		field("BDAY",strings.Replace(p.GetValueFirstText(),"-","",-1))
+	}
+	for _,a := range c.Addresses(){
+		parts := []string{a.PostOfficeBox,a.ExtendedAddress,a.StreetAddress,a.Locality,a.Region,a.PostalCode,a.Country}
+		for i,part := range parts{
+			parts[i] = esc(part)
+		}
+		if adr := strings.Join(parts,",");strings.Trim(adr,",") != ""{
+			field("ADR",adr)
+		}
+	}
+	for _,p := range c[PropUrl]{
+		field("URL",esc(joinValue(p.Value)))
+	}
+	for _,p := range c[PropNickName]{
+		field("NICKNAME",esc(joinValue(p.Value)))
+	}
+	if p := c.Get(PropOrg);p != nil{
+		field("ORG",esc(strings.Join(p.GetValueTextList(),", ")))
+	}
+	b.WriteString(";")
+	return b.String(),nil
+}
+
+/*
+decode a MECARD string to a vCard 4.0 card,unknown fields are ignored
+ */
+func UnmarshalMeCard(s string) (Card,error) {
+	s = strings.TrimSpace(s)
+	if len(s) < len(meCardPrefix) || !strings.EqualFold(s[:len(meCardPrefix)],meCardPrefix){
+		return nil,errors.New("vcard:mecard:missing MECARD: prefix")
+	}
+	c := make(Card)
+	c.Set(PropVersion,&Property{Name:PropVersion,Value:[][]string{{"4.0"}}})
+	add := func(name string,value [][]string,types ...string) {
+		p := &Property{Name:name,Value:value}
+		for _,t := range types{
+			p.AddParam(ParamType,t)
+		}
+		c.Add(name,p)
+	}
+	for _,f := range splitEscaped(s[len(meCardPrefix):],';'){
+		kv := splitEscaped(f,':')
+		if len(kv) < 2{
+			continue
+		}
+		key := strings.ToUpper(strings.TrimSpace(kv[0]))
+		//an unescaped ':' in the value belong to it,e.g. URL:http://
+		raw := strings.Join(kv[1:],":")
+		value := unescapeMeCard(raw)
+		if value == ""{
+			continue
+		}
+		switch key {
+		case "N":
+			parts := splitEscaped(raw,',')
+			family := unescapeMeCard(parts[0])
+			given := ""
+			if len(parts) > 1{
+				given = unescapeMeCard(parts[1])
+			}
+			c.AddName(&Name{FamilyName:family,GivenName:given})
+			fn := strings.TrimSpace(given+" "+family)
+			c.SetValue(PropFN,[][]string{{fn}})
+		case "TEL":
+			add(PropTel,[][]string{{value}})
+		case "TEL-AV":
+			add(PropTel,[][]string{{value}},"video")
+		case "EMAIL":
+			add(PropEmail,[][]string{{value}})
+		case "NOTE":
+			add(PropNote,[][]string{{value}})
+		case "BDAY":
+			add(PropBday,[][]string{{value}})
+		case "ADR":
+			parts := splitEscaped(raw,',')
+			adr := &Address{}
+			fields := []*string{&adr.PostOfficeBox,&adr.ExtendedAddress,&adr.StreetAddress,&adr.Locality,&adr.Region,&adr.PostalCode,&adr.Country}
+			if len(parts) == 1{
+				//a single line address
+				adr.StreetAddress = value
+			}else{
+				for i,part := range parts{
+					if i < len(fields){
+						*fields[i] = unescapeMeCard(part)
+					}
+				}
+			}
+			c.AddAdress(adr)
+			adr.Property.Name = PropAdr
+		case "URL":
+			add(PropUrl,[][]string{{value}})
+		case "NICKNAME":
+			add(PropNickName,[][]string{{value}})
+		case "ORG":
+			add(PropOrg,[][]string{{value}})
+		}
+	}
+	if c.Get(PropFN) == nil{
+		return nil,errors.New("vcard:mecard:missing N field")
+	}
+	return c,nil
+}
+
+/*
+split s by the sep that is not escaped by '\',the parts are still escaped
+ */
+func splitEscaped(s string,sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0;i < len(s);i++{
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts,s[start:i])
+			start = i+1
+		}
+	}
+	return append(parts,s[start:])
+}
+
+func unescapeMeCard(s string) string {
+	var b strings.Builder
+	for i := 0;i < len(s);i++{
+		if s[i] == '\\' && i+1 < len(s){
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package go_vcard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarshalMeCard(t *testing.T) {
+	card := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		PropN:       {{Name: PropN, Value: [][]string{{"Bloggs"}, {"Joe"}, {""}, {""}, {""}}}},
+		PropTel:     {{Name: PropTel, Value: [][]string{{"tel:+1-555-0100"}}}},
+		PropEmail:   {{Name: PropEmail, Value: [][]string{{"joe@example.com"}}}},
+		PropNote:    {{Name: PropNote, Value: [][]string{{"Badge; row 3: seat 4"}}}},
+		PropUrl:     {{Name: PropUrl, Value: [][]string{{"http://example.com/"}}}},
+		PropAdr:     {{Name: PropAdr, Value: [][]string{{""}, {""}, {"1 Main St"}, {"Springfield"}, {"IL"}, {"12345"}, {"USA"}}}},
+		PropPhoto:   {{Name: PropPhoto, Value: [][]string{{"http://example.com/joe.jpg"}}}},
+	}
+
+	s, err := MarshalMeCard(card)
+	if err != nil {
+		t.Fatal("Expected no error when encoding MeCard, got:", err)
+	}
+	expected := `MECARD:N:Bloggs,Joe;TEL:+1-555-0100;EMAIL:joe@example.com;NOTE:Badge\; row 3\: seat 4;ADR:,,1 Main St,Springfield,IL,12345,USA;URL:http\://example.com/;;`
+	if s != expected {
+		t.Errorf("Expected MeCard\n%s\nbut got\n%s", expected, s)
+	}
+
+	got, err := UnmarshalMeCard(s)
+	if err != nil {
+		t.Fatal("Expected no error when decoding MeCard, got:", err)
+	}
+	if v := got.Get(PropFN).GetValueFirstText(); v != "Joe Bloggs" {
+		t.Errorf("Expected FN Joe Bloggs but got %q", v)
+	}
+	if n := got.Name(); n.FamilyName != "Bloggs" || n.GivenName != "Joe" {
+		t.Errorf("Expected N Bloggs,Joe but got %+v", n)
+	}
+	if v := got.Get(PropNote).GetValueFirstText(); v != "Badge; row 3: seat 4" {
+		t.Errorf("Expected unescaped NOTE but got %q", v)
+	}
+	if a := got.Address(); a.Locality != "Springfield" || a.Country != "USA" {
+		t.Errorf("Expected ADR components but got %+v", a)
+	}
+	if v := got.Get(PropUrl).GetValueFirstText(); v != "http://example.com/" {
+		t.Errorf("Expected URL but got %q", v)
+	}
+
+	if got, err := UnmarshalMeCard("MECARD:N:Doe,Jane;URL:http://example.com;TEL-AV:+15550100;;"); err != nil {
+		t.Error("Expected no error when decoding MeCard with unescaped URL, got:", err)
+	} else if v := got.Get(PropUrl).GetValueFirstText(); v != "http://example.com" || !got.Get(PropTel).IsHasType("video") {
+		t.Errorf("Expected URL and video TEL but got %q and %v", v, got.Get(PropTel).Params)
+	}
+	if _, err := UnmarshalMeCard("BEGIN:VCARD"); err == nil {
+		t.Error("Expected an error for a string that is not a MeCard")
+	}
+}
+
+func TestUnmarshalMeCard_encodeDecode(t *testing.T) {
+	card, err := UnmarshalMeCard("MECARD:N:Doe,Jane;TEL:+15550100;EMAIL:jane@example.com;;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := card.Get(PropVersion); p == nil || p.Name != PropVersion {
+		t.Errorf("Expected a VERSION property named %s but got %+v", PropVersion, p)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "\r\nVERSION:4.0\r\n") {
+		t.Errorf("Expected VERSION:4.0 in %q", b.String())
+	}
+	got, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding the card of a MeCard, got:", err)
+	}
+	if v := got.Get(PropVersion).GetValueFirstText(); v != "4.0" {
+		t.Errorf("Expected VERSION 4.0 but got %q", v)
+	}
+	if n := got.Name(); n.FamilyName != "Doe" || n.GivenName != "Jane" {
+		t.Errorf("Expected N Doe,Jane but got %+v", n)
+	}
+	if v := got.Get(PropEmail).GetValueFirstText(); v != "jane@example.com" {
+		t.Errorf("Expected EMAIL jane@example.com but got %q", v)
+	}
+}
@@ -0,0 +1,275 @@
+package go_vcard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+/*
+a PHOTO,LOGO,SOUND or KEY property.the content is either an external URI,
+inline base64 of vCard 2.1/3.0(ENCODING=b) or a data: URI of vCard 4.0.inline
+content is kept encoded and decoded by Reader while reading
+ */
+type Media struct {
+	*Property
+
+	MediaType string //e.g. image/jpeg,empty if unknown
+	URI string //external URI,empty for inline content
+
+	b64 string //base64 of inline content
+	raw []byte //inline content not in base64,e.g. a percent-encoded data: URI
+}
+
+func newMedia(p *Property) (*Media,error) {
+	m := &Media{Property:p}
+	_,m.MediaType = findParam(p.Params,ParamMediatype)
+	_,enc := findParam(p.Params,paramEncoding)
+	_,typ := findParam(p.Params,ParamValue)
+	//the base64 of a large media is taken from the value without copying
+	vals := p.Value
+	value := ""
+	if len(vals) == 1 && len(vals[0]) == 1{
+		value = vals[0][0]
+	}else if !isDataBase64(vals){
+		value = joinValue(vals)
+	}
+	switch {
+	case strings.EqualFold(enc,"b") || strings.EqualFold(enc,"BASE64"):
+		m.b64 = value
+		if strings.ContainsAny(value," \t\r\n"){
+			m.b64 = strings.Join(strings.Fields(value),"")
+		}
+		if types := p.Params[ParamType];m.MediaType == "" && len(types) > 0{
+			//vCard 3.0 give the format in TYPE,e.g. TYPE=JPEG
+			m.MediaType = mediaTypeOf(p.Name,types[0])
+		}
+	case isDataBase64(vals):
+		m.b64 = vals[1][1]
+		m.setDataMediaType(vals[0][0][5:])
+	case len(value) >= 5 && strings.EqualFold(value[:5],"data:"):
+		i := strings.IndexByte(value,',')
+		if i < 0{
+			return nil,fmt.Errorf("vcard:%s:invalid data URI,missing ','",p.Name)
+		}
+		header,data := value[5:i],value[i+1:]
+		if strings.HasSuffix(strings.ToLower(header),";base64"){
+			header = header[:len(header)-len(";base64")]
+			m.b64 = data
+		}else{
+			raw,err := url.PathUnescape(data)
+			if err != nil{
+				return nil,fmt.Errorf("vcard:%s:invalid data URI:%v",p.Name,err)
+			}
+			m.raw = []byte(raw)
+		}
+		m.setDataMediaType(header)
+	case strings.EqualFold(typ,"text"):
+		//e.g. KEY;VALUE=text of vCard 3.0
+		m.raw = []byte(value)
+	default:
+		m.URI = value
+	}
+	if m.MediaType == "" && !m.IsExternal(){
+		head := make([]byte,512)
+		r,_ := m.Reader()
+		n,err := io.ReadFull(r,head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF{
+			return nil,fmt.Errorf("vcard:%s:invalid base64 content:%v",p.Name,err)
+		}
+		m.MediaType = SniffMediaType(head[:n])
+	}
+	return m,nil
+}
+
+/*
+a base64 data: URI is parsed as {"data:image/jpeg"},{"base64",content}
+ */
+func isDataBase64(vals [][]string) bool {
+	return len(vals) == 2 && len(vals[0]) == 1 && len(vals[1]) == 2 &&
+		strings.EqualFold(vals[1][0],"base64") && len(vals[0][0]) >= 5 && strings.EqualFold(vals[0][0][:5],"data:")
+}
+
+/*
+set the media type from the header of a data: URI,e.g. image/jpeg;name=a.jpg
+ */
+func (m *Media) setDataMediaType(header string)  {
+	if mt := strings.SplitN(header,";",2)[0];mt != ""{
+		m.MediaType = strings.ToLower(mt)
+	}
+}
+
+/*
+the content is not inline but at URI
+ */
+func (m *Media) IsExternal() bool {
+	return m.b64 == "" && m.raw == nil
+}
+
+/*
+return a reader of the decoded inline content,an error for an external URI
+ */
+func (m *Media) Reader() (io.Reader,error) {
+	switch {
+	case m.b64 != "":
+		return base64.NewDecoder(base64.StdEncoding,strings.NewReader(m.b64)),nil
+	case m.raw != nil:
+		return bytes.NewReader(m.raw),nil
+	}
+	return nil,fmt.Errorf("vcard:media is not inline,it is at %q",m.URI)
+}
+
+/*
+return the decoded inline content
+ */
+func (m *Media) Bytes() ([]byte,error) {
+	r,err := m.Reader()
+	if err != nil{
+		return nil,err
+	}
+	return ioutil.ReadAll(r)
+}
+
+/*
+the data: URI of inline content,or the external URI
+ */
+func (m *Media) DataURI() string {
+	if m.IsExternal(){
+		return m.URI
+	}
+	b64 := m.b64
+	if b64 == ""{
+		b64 = base64.StdEncoding.EncodeToString(m.raw)
+	}
+	return "data:"+m.MediaType+";base64,"+b64
+}
+
+/*
+known file signatures,in the order they are tried
+ */
+var mediaSignatures = []struct {
+	offset int
+	sig string
+	mediatype string
+}{
+	{0,"\xff\xd8\xff","image/jpeg"},
+	{0,"\x89PNG\r\n\x1a\n","image/png"},
+	{0,"GIF87a","image/gif"},
+	{0,"GIF89a","image/gif"},
+	{8,"WEBP","image/webp"},
+	{0,"BM","image/bmp"},
+	{0,"\x00\x00\x01\x00","image/x-icon"},
+	{0,"<svg","image/svg+xml"},
+	{8,"WAVE","audio/wav"},
+	{0,"OggS","audio/ogg"},
+	{0,"fLaC","audio/flac"},
+	{0,"ID3","audio/mpeg"},
+	{0,"\xff\xfb","audio/mpeg"},
+	{0,".snd","audio/basic"},
+	{4,"ftypM4A","audio/mp4"},
+	{0,"-----BEGIN PGP","application/pgp-keys"},
+	{0,"-----BEGIN CERTIFICATE","application/pkix-cert"},
+	{0,"\x30\x82","application/pkix-cert"},
+}
+
+/*
+guess the media type of content from its first bytes,
+application/octet-stream if it is unknown
+ */
+func SniffMediaType(head []byte) string {
+	for _,s := range mediaSignatures{
+		if len(head) >= s.offset+len(s.sig) && string(head[s.offset:s.offset+len(s.sig)]) == s.sig{
+			return s.mediatype
+		}
+	}
+	return "application/octet-stream"
+}
+
+/*
+return all media of PHOTO,LOGO,SOUND or KEY
+ */
+func (c Card) Media(k string) ([]*Media,error) {
+	props := c[k]
+	if props == nil{
+		return nil,nil
+	}
+	media := make([]*Media,len(props))
+	for i,p := range props{
+		m,err := newMedia(p)
+		if err != nil{
+			return nil,err
+		}
+		media[i] = m
+	}
+	return media,nil
+}
+
+/*
+set k to a data: URI of the content read from r,the base64 is encoded while
+reading.an empty mediatype is sniffed from the content
+ */
+func (c Card) SetMediaFrom(k,mediatype string,r io.Reader) error {
+	if mediatype == ""{
+		br := bufio.NewReaderSize(r,512)
+		head,err := br.Peek(512)
+		if err != nil && err != io.EOF{
+			return err
+		}
+		mediatype = SniffMediaType(head)
+		r = br
+	}
+	var b strings.Builder
+	w := base64.NewEncoder(base64.StdEncoding,&b)
+	if _,err := io.Copy(w,r);err != nil{
+		return err
+	}
+	if err := w.Close();err != nil{
+		return err
+	}
+	//same as parseValues of the data: URI,without copying the base64
+	c.Set(k,&Property{Name:k,Value:[][]string{{"data:"+mediatype},{"base64",b.String()}}})
+	return nil
+}
+
+/*
+set k to a data: URI of data,an empty mediatype is sniffed from data
+ */
+func (c Card) SetMedia(k,mediatype string,data []byte)  {
+	//reading from bytes.Reader never fail
+	c.SetMediaFrom(k,mediatype,bytes.NewReader(data))
+}
+
+/*
+set k to an external URI,mediatype is optional
+ */
+func (c Card) SetMediaURI(k,uri,mediatype string) error {
+	if uri == ""{
+		return errors.New("vcard:empty media URI")
+	}
+	//the URI is not escaped text,e.g. a \ or , in the URI is kept as is
+	p := &Property{Name:k}
+	p.SetValueText(uri)
+	if mediatype != ""{
+		p.SetParam(ParamMediatype,mediatype)
+	}
+	c.Set(k,p)
+	return nil
+}
+
+func (c Card) Photos() ([]*Media,error) {
+	return c.Media(PropPhoto)
+}
+
+func (c Card) SetPhoto(mediatype string,data []byte)  {
+	c.SetMedia(PropPhoto,mediatype,data)
+}
+
+func (c Card) SetPhotoURI(uri,mediatype string) error {
+	return c.SetMediaURI(PropPhoto,uri,mediatype)
+}
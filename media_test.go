@@ -0,0 +1,106 @@
+package go_vcard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var testJPEG = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01 not really a jpeg")
+
+func TestCard_Photos(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString(testJPEG)
+	card, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"PHOTO;ENCODING=b;TYPE=JPEG:" + b64[:20] + "\r\n " + b64[20:] + "\r\n" +
+		"PHOTO:data:;base64," + b64 + "\r\n" +
+		"PHOTO;MEDIATYPE=image/png:http://example.com/joe.png\r\n" +
+		"LOGO:data:image/svg+xml,%3Csvg%2F%3E\r\n" +
+		"END:VCARD\r\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	photos, err := card.Photos()
+	if err != nil {
+		t.Fatal("Expected no error when getting photos, got:", err)
+	}
+	if len(photos) != 3 {
+		t.Fatalf("Expected 3 photos but got %d", len(photos))
+	}
+	for i, m := range photos[:2] {
+		if m.MediaType != "image/jpeg" || m.IsExternal() {
+			t.Errorf("Photo %d: expected an inline image/jpeg but got %+v", i, m)
+		}
+		r, err := m.Reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(data, testJPEG) {
+			t.Errorf("Photo %d: unexpected content %q, %v", i, data, err)
+		}
+	}
+	if m := photos[2]; !m.IsExternal() || m.URI != "http://example.com/joe.png" || m.MediaType != "image/png" {
+		t.Errorf("Unexpected external photo %+v", m)
+	} else if _, err := m.Reader(); err == nil {
+		t.Error("Expected an error when reading an external photo")
+	}
+
+	if logos, err := card.Media(PropLogo); err != nil || len(logos) != 1 {
+		t.Errorf("Unexpected logos %v, %v", logos, err)
+	} else if data, err := logos[0].Bytes(); err != nil || string(data) != "<svg/>" || logos[0].MediaType != "image/svg+xml" {
+		t.Errorf("Unexpected logo %q, %v", data, err)
+	}
+}
+
+func TestCard_SetPhoto(t *testing.T) {
+	card := Card{PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}}}
+	card.SetPhoto("", testJPEG)
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal(err)
+	}
+	expected := "PHOTO:data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(testJPEG)
+	if got := strings.Replace(b.String(), "\r\n ", "", -1); !strings.Contains(got, expected) {
+		t.Errorf("Expected %q in\n%s", expected, got)
+	}
+
+	decoded, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if photos, err := decoded.Photos(); err != nil || len(photos) != 1 {
+		t.Fatalf("Unexpected photos %v, %v", photos, err)
+	} else if data, err := photos[0].Bytes(); err != nil || !bytes.Equal(data, testJPEG) {
+		t.Errorf("Unexpected photo after round trip %q, %v", data, err)
+	}
+
+	if err := card.SetPhotoURI("http://example.com/joe.jpg", "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if p := card.Get(PropPhoto); p.GetValueFirstText() != "http://example.com/joe.jpg" || p.GetFirstParamVal(ParamMediatype) != "image/jpeg" {
+		t.Errorf("Unexpected PHOTO %+v", p)
+	}
+	uri := `http://example.com/a\b,c;d.jpg`
+	if err := card.SetPhotoURI(uri, ""); err != nil {
+		t.Fatal(err)
+	}
+	if photos, err := card.Photos(); err != nil || len(photos) != 1 || photos[0].URI != uri {
+		t.Errorf("Expected photo URI %q but got %v, %v", uri, photos, err)
+	}
+	if err := card.SetPhotoURI("", ""); err == nil {
+		t.Error("Expected an error for an empty photo URI")
+	}
+
+	card.SetMedia(PropSound, "", []byte("OggS\x00\x02"))
+	if sounds, err := card.Media(PropSound); err != nil || sounds[0].MediaType != "audio/ogg" {
+		t.Errorf("Unexpected sounds %v, %v", sounds, err)
+	}
+	card[PropKey] = []*Property{{Name: PropKey, Params: map[string][]string{paramEncoding: {"b"}}, Value: [][]string{{"!!!!"}}}}
+	if _, err := card.Media(PropKey); err == nil {
+		t.Error("Expected an error for invalid base64")
+	}
+}
@@ -0,0 +1,259 @@
+package go_vcard
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+/*
+an EMAIL property,Address is the addr-spec,e.g. joe@example.com
+ */
+type Email struct {
+	*Property
+
+	Address string
+	Types []string //lower case,e.g. home,work
+	Pref int //1 is the most preferred,0 if not set
+}
+
+/*
+a URL property
+ */
+type URL struct {
+	*Property
+
+	URL *url.URL
+	Types []string
+	Pref int
+}
+
+/*
+an IMPP property,the URI of an instant messaging account,e.g.
+xmpp:alice@example.com
+ */
+type IMPP struct {
+	*Property
+
+	URI *url.URL
+	Types []string
+	Pref int
+}
+
+/*
+parse an email address,a "mailto:" prefix and a display name are accepted
+ */
+func parseEmail(s string) (string,error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 7 && strings.EqualFold(s[:7],"mailto:"){
+		s = s[7:]
+	}
+	a,err := mail.ParseAddress(s)
+	if err != nil{
+		return "",fmt.Errorf("vcard:invalid email address %q:%v",s,err)
+	}
+	return a.Address,nil
+}
+
+/*
+parse an absolute URI,e.g. http://example.com or skype:joe.bloggs
+ */
+func parseURI(prop,s string) (*url.URL,error) {
+	u,err := url.Parse(strings.TrimSpace(s))
+	if err != nil{
+		return nil,fmt.Errorf("vcard:invalid %s URI %q:%v",prop,s,err)
+	}
+	if u.Scheme == ""{
+		return nil,fmt.Errorf("vcard:%s value %q is not a URI,missing scheme",prop,s)
+	}
+	if u.Opaque == "" && u.Host == "" && u.Path == ""{
+		return nil,fmt.Errorf("vcard:%s value %q is not a URI,missing address",prop,s)
+	}
+	return u,nil
+}
+
+func newEmail(p *Property) (*Email,error) {
+	addr,err := parseEmail(joinValue(p.Value))
+	if err != nil{
+		return nil,err
+	}
+	e := &Email{Property:p,Address:addr}
+	e.Types,e.Pref = typesAndPref(p)
+	return e,nil
+}
+
+func (e *Email) HasType(typ string) bool {
+	return hasType(e.Types,typ)
+}
+
+func (e *Email) property() (*Property,error) {
+	addr,err := parseEmail(e.Address)
+	if err != nil{
+		return nil,err
+	}
+	if e.Property == nil{
+		e.Property = &Property{Name:PropEmail}
+	}
+	e.Property.Value = [][]string{{addr}}
+	setTypesAndPref(e.Property,e.Types,e.Pref)
+	return e.Property,nil
+}
+
+func newURL(p *Property) (*URL,error) {
+	u,err := parseURI(PropUrl,joinValue(p.Value))
+	if err != nil{
+		return nil,err
+	}
+	l := &URL{Property:p,URL:u}
+	l.Types,l.Pref = typesAndPref(p)
+	return l,nil
+}
+
+func (l *URL) HasType(typ string) bool {
+	return hasType(l.Types,typ)
+}
+
+func (l *URL) property() (*Property,error) {
+	if l.URL == nil{
+		return nil,fmt.Errorf("vcard:URL is nil")
+	}
+	u,err := parseURI(PropUrl,l.URL.String())
+	if err != nil{
+		return nil,err
+	}
+	if l.Property == nil{
+		l.Property = &Property{Name:PropUrl}
+	}
+	l.Property.Value = [][]string{{u.String()}}
+	setTypesAndPref(l.Property,l.Types,l.Pref)
+	return l.Property,nil
+}
+
+func newIMPP(p *Property) (*IMPP,error) {
+	u,err := parseURI(PropImpp,joinValue(p.Value))
+	if err != nil{
+		return nil,err
+	}
+	im := &IMPP{Property:p,URI:u}
+	im.Types,im.Pref = typesAndPref(p)
+	return im,nil
+}
+
+func (im *IMPP) HasType(typ string) bool {
+	return hasType(im.Types,typ)
+}
+
+func (im *IMPP) property() (*Property,error) {
+	if im.URI == nil{
+		return nil,fmt.Errorf("vcard:IMPP URI is nil")
+	}
+	u,err := parseURI(PropImpp,im.URI.String())
+	if err != nil{
+		return nil,err
+	}
+	if im.Property == nil{
+		im.Property = &Property{Name:PropImpp}
+	}
+	im.Property.Value = [][]string{{u.String()}}
+	setTypesAndPref(im.Property,im.Types,im.Pref)
+	return im.Property,nil
+}
+
+/*
+return all emails,an error if one of them is malformed
+ */
+func (c Card) Emails() ([]*Email,error) {
+	props := c[PropEmail]
+	if props == nil{
+		return nil,nil
+	}
+	emails := make([]*Email,len(props))
+	for i,p := range props{
+		e,err := newEmail(p)
+		if err != nil{
+			return nil,err
+		}
+		emails[i] = e
+	}
+	return emails,nil
+}
+
+/*
+return the preferred email,nil if the card has no email
+ */
+func (c Card) PreferredEmail() (*Email,error) {
+	p := c.Pref(PropEmail)
+	if p == nil{
+		return nil,nil
+	}
+	return newEmail(p)
+}
+
+/*
+add an email,a malformed address is rejected
+ */
+func (c Card) AddEmail(e *Email) error {
+	p,err := e.property()
+	if err != nil{
+		return err
+	}
+	c.Add(PropEmail,p)
+	return nil
+}
+
+/*
+return all URLs,an error if one of them is not an absolute URI
+ */
+func (c Card) URLs() ([]*URL,error) {
+	props := c[PropUrl]
+	if props == nil{
+		return nil,nil
+	}
+	urls := make([]*URL,len(props))
+	for i,p := range props{
+		l,err := newURL(p)
+		if err != nil{
+			return nil,err
+		}
+		urls[i] = l
+	}
+	return urls,nil
+}
+
+func (c Card) AddURL(l *URL) error {
+	p,err := l.property()
+	if err != nil{
+		return err
+	}
+	c.Add(PropUrl,p)
+	return nil
+}
+
+/*
+return all IMPPs,an error if one of them is not an absolute URI,e.g. IMPP:joe
+ */
+func (c Card) IMPPs() ([]*IMPP,error) {
+	props := c[PropImpp]
+	if props == nil{
+		return nil,nil
+	}
+	impps := make([]*IMPP,len(props))
+	for i,p := range props{
+		im,err := newIMPP(p)
+		if err != nil{
+			return nil,err
+		}
+		impps[i] = im
+	}
+	return impps,nil
+}
+
+func (c Card) AddIMPP(im *IMPP) error {
+	p,err := im.property()
+	if err != nil{
+		return err
+	}
+	c.Add(PropImpp,p)
+	return nil
+}
@@ -0,0 +1,84 @@
+package go_vcard
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCard_Emails(t *testing.T) {
+	card := Card{
+		PropEmail: {
+			{Name: PropEmail, Params: map[string][]string{ParamType: {"work"}}, Value: [][]string{{"joe@work.example.com"}}},
+			{Name: PropEmail, Params: map[string][]string{ParamType: {"HOME"}, ParamPref: {"1"}}, Value: [][]string{{"mailto:joe@example.com"}}},
+		},
+	}
+
+	emails, err := card.Emails()
+	if err != nil {
+		t.Fatal("Expected no error when getting emails, got:", err)
+	}
+	if len(emails) != 2 || emails[0].Address != "joe@work.example.com" || !emails[0].HasType("work") {
+		t.Errorf("Unexpected emails %+v", emails)
+	}
+	if e, err := card.PreferredEmail(); err != nil || e.Address != "joe@example.com" || e.Pref != 1 || !e.HasType("home") {
+		t.Errorf("Unexpected preferred email %+v, %v", e, err)
+	}
+
+	if err := card.AddEmail(&Email{Address: "jane@example.com", Types: []string{"home"}}); err != nil {
+		t.Error("Expected no error when adding an email, got:", err)
+	} else if p := card[PropEmail][2]; p.Name != PropEmail || p.GetValueFirstText() != "jane@example.com" || !p.IsHasType("home") {
+		t.Errorf("Unexpected added EMAIL %+v", p)
+	}
+	for _, addr := range []string{"joe", "joe@", "@example.com", "joe bloggs@example.com"} {
+		if err := card.AddEmail(&Email{Address: addr}); err == nil {
+			t.Errorf("Expected an error when adding email %q", addr)
+		}
+	}
+	if len(card[PropEmail]) != 3 {
+		t.Error("Expected malformed emails not to be added")
+	}
+
+	card.AddValue(PropEmail, [][]string{{"not an email"}})
+	if _, err := card.Emails(); err == nil {
+		t.Error("Expected an error for a malformed EMAIL")
+	}
+}
+
+func TestCard_URLsAndIMPPs(t *testing.T) {
+	card := Card{
+		PropUrl:  {{Name: PropUrl, Params: map[string][]string{ParamType: {"home"}}, Value: [][]string{{"http://joebloggs.com"}}}},
+		PropImpp: {{Name: PropImpp, Params: map[string][]string{ParamPref: {"1"}}, Value: [][]string{{"skype:joe.bloggs"}}}},
+	}
+
+	if urls, err := card.URLs(); err != nil {
+		t.Fatal("Expected no error when getting URLs, got:", err)
+	} else if len(urls) != 1 || urls[0].URL.Host != "joebloggs.com" || !urls[0].HasType("home") {
+		t.Errorf("Unexpected URLs %+v", urls)
+	}
+	if impps, err := card.IMPPs(); err != nil {
+		t.Fatal("Expected no error when getting IMPPs, got:", err)
+	} else if len(impps) != 1 || impps[0].URI.Scheme != "skype" || impps[0].URI.Opaque != "joe.bloggs" || impps[0].Pref != 1 {
+		t.Errorf("Unexpected IMPPs %+v", impps)
+	}
+
+	if err := card.AddIMPP(&IMPP{URI: &url.URL{Scheme: "xmpp", Opaque: "alice@example.com"}}); err != nil {
+		t.Error("Expected no error when adding an IMPP, got:", err)
+	} else if v := card[PropImpp][1].GetValueFirstText(); v != "xmpp:alice@example.com" {
+		t.Errorf("Expected IMPP xmpp:alice@example.com but got %q", v)
+	}
+	if err := card.AddIMPP(&IMPP{URI: &url.URL{Path: "joe"}}); err == nil {
+		t.Error("Expected an error when adding an IMPP without scheme")
+	}
+	if err := card.AddURL(&URL{}); err == nil {
+		t.Error("Expected an error when adding an empty URL")
+	}
+
+	card.AddValue(PropImpp, [][]string{{"joe"}})
+	if _, err := card.IMPPs(); err == nil {
+		t.Error("Expected an error for IMPP:joe")
+	}
+	card.SetValue(PropUrl, [][]string{{"www.example.com"}})
+	if _, err := card.URLs(); err == nil {
+		t.Error("Expected an error for a URL without scheme")
+	}
+}
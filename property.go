@@ -1,12 +1,56 @@
 package go_vcard
 
-import "strings"
+import (
+	"reflect"
+	"strings"
+)
 
 type Property struct {
 	Group string
 	Name string
 	Params map[string][]string
 	Value [][]string //first seperate by ';',secondly seperate by ','
+
+	src *source //where the property is decoded from,see Decoder.Lossless
+}
+
+/*
+a property as read by a lossless Decoder
+ */
+type source struct {
+	seq int //position in the card,start at 1
+	line string //unfolded content line,empty if it can not be written back
+	version string
+	decoded Property
+}
+
+/*
+return a deep copy of the property,without its source
+ */
+func (p *Property) copy() Property {
+	cp := Property{Group:p.Group,Name:p.Name}
+	if p.Params != nil{
+		cp.Params = copyParams(p.Params)
+	}
+	if p.Value != nil{
+		cp.Value = make([][]string,len(p.Value))
+		for i,vals := range p.Value{
+			cp.Value[i] = append([]string(nil),vals...)
+		}
+	}
+	return cp
+}
+
+/*
+the property is not changed since it is decoded
+ */
+func (p *Property) unchanged() bool {
+	if p.src == nil{
+		return false
+	}
+	d := &p.src.decoded
+	return p.Group == d.Group && p.Name == d.Name &&
+		reflect.DeepEqual(p.Params,d.Params) && reflect.DeepEqual(p.Value,d.Value)
 }
 
 func (p *Property) GetValueTextList() []string {
@@ -18,6 +62,21 @@ func (p *Property) GetValueTextList() []string {
 	}
 	return tl
 }
+/*
+get the whole value,components joined by ';' and lists by ','
+ */
+func (p *Property) GetValueText() string {
+	return joinValue(p.Value)
+}
+
+/*
+set the value from its joined form,the inverse of GetValueText.';' and ','
+are split without unescaping,e.g. a URI
+ */
+func (p *Property) SetValueText(s string)  {
+	p.Value = splitValue(s)
+}
+
 /*
 get value first value,if not return ""
  */
@@ -42,6 +101,9 @@ func (p *Property) GetFirstParamVal(param string) string {
 Add a param,the key is existed
  */
 func (p *Property) AddParam(key,val string)  {
+	if p.Params == nil{
+		p.Params = make(map[string][]string)
+	}
 	p.Params[key] = append(p.Params[key],val)
 }
 
@@ -49,9 +111,59 @@ func (p *Property) AddParam(key,val string)  {
 set a param,new a param
  */
 func (p *Property) SetParam(key,val string)  {
+	if p.Params == nil{
+		p.Params = make(map[string][]string)
+	}
 	p.Params[key] = []string{val}
 }
 
+/*
+value type of the properties without VALUE param,other properties are "text"
+and the x-name properties are "unknown"
+ */
+var defaultValueTypes = map[string]string{
+	PropSource: "uri",
+	PropPhoto: "uri",
+	PropBday: "date-and-or-time",
+	PropAnniversary: "date-and-or-time",
+	PropImpp: "uri",
+	PropLang: "language-tag",
+	PropGEO: "uri",
+	PropLogo: "uri",
+	PropMember: "uri",
+	PropRelated: "uri",
+	PropRev: "timestamp",
+	PropSound: "uri",
+	PropUid: "uri",
+	PropUrl: "uri",
+	PropKey: "uri",
+	PropFBurl: "uri",
+	PropCaladruri: "uri",
+	PropCalUri: "uri",
+}
+
+func defaultValueType(name string) string {
+	name = strings.ToUpper(name)
+	if t,ok := defaultValueTypes[name];ok{
+		return t
+	}
+	if strings.HasPrefix(name,"X-"){
+		return "unknown"
+	}
+	return "text"
+}
+
+/*
+return the value type of the property,given by VALUE param or the default
+type of the property
+ */
+func (p *Property) ValueType() string {
+	if _,v := findParam(p.Params,ParamValue);v != ""{
+		return strings.ToLower(v)
+	}
+	return defaultValueType(p.Name)
+}
+
 /*
 get list of param types
  */
@@ -87,37 +199,27 @@ type Name struct {
 }
 
 func newName(p *Property) *Name {
-	vals := p.Value[0]
 	return &Name{
 		Property:        p,
-		FamilyName:      vals[0],
-		GivenName:       vals[1],
-		AdditionalName:  vals[2],
-		HonorificPrefix: vals[3],
-		HonorificSuffix: vals[4],
+		FamilyName:      component(p.Value,0),
+		GivenName:       component(p.Value,1),
+		AdditionalName:  component(p.Value,2),
+		HonorificPrefix: component(p.Value,3),
+		HonorificSuffix: component(p.Value,4),
 	}
 }
 
 func (n *Name) property() *Property {
 	if n.Property == nil{
 		n.Property = new(Property)
-		n.Property.Value = [][]string{{
-			n.FamilyName,
-			n.GivenName,
-			n.AdditionalName,
-			n.HonorificPrefix,
-			n.HonorificSuffix,
-		}}
-	}else{
-		n.Property.Value = append(n.Property.Value,[]string{
-			n.FamilyName,
-			n.GivenName,
-			n.AdditionalName,
-			n.HonorificPrefix,
-			n.HonorificSuffix,
-		})
 	}
-
+	n.Property.Value = [][]string{
+		listOf(n.FamilyName),
+		listOf(n.GivenName),
+		listOf(n.AdditionalName),
+		listOf(n.HonorificPrefix),
+		listOf(n.HonorificSuffix),
+	}
 	return n.Property
 }
 
@@ -134,16 +236,15 @@ type Address struct {
 }
 
 func newAddress(p *Property) *Address {
-	vals := p.Value[0]
 	return &Address{
 		Property:        p,
-		PostOfficeBox:   vals[0],
-		ExtendedAddress: vals[1],
-		StreetAddress:   vals[2],
-		Locality:        vals[3],
-		Region:          vals[4],
-		PostalCode:      vals[5],
-		Country:         vals[6],
+		PostOfficeBox:   component(p.Value,0),
+		ExtendedAddress: component(p.Value,1),
+		StreetAddress:   component(p.Value,2),
+		Locality:        component(p.Value,3),
+		Region:          component(p.Value,4),
+		PostalCode:      component(p.Value,5),
+		Country:         component(p.Value,6),
 	}
 }
 
@@ -151,16 +252,32 @@ func (a *Address) property() *Property {
 	if a.Property == nil {
 		a.Property = new(Property)
 	}
-	a.Property.Value = append(a.Property.Value,[]string{
-		a.PostOfficeBox,
-		a.ExtendedAddress,
-		a.StreetAddress,
-		a.Locality,
-		a.Region,
-		a.PostalCode,
-		a.Country,
-	})
+	a.Property.Value = [][]string{
+		listOf(a.PostOfficeBox),
+		listOf(a.ExtendedAddress),
+		listOf(a.StreetAddress),
+		listOf(a.Locality),
+		listOf(a.Region),
+		listOf(a.PostalCode),
+		listOf(a.Country),
+	}
 	return  a.Property
 }
 
+/*
+text of the i-th component of a structured value,"" when it is missing.
+the items of a list are joined by ',',e.g. Mr.,Dr.
+ */
+func component(vals [][]string,i int) string {
+	if i >= len(vals){
+		return ""
+	}
+	return strings.Join(vals[i],",")
+}
 
+/*
+the list of a component text,the inverse of component
+ */
+func listOf(s string) []string {
+	return strings.Split(s,",")
+}
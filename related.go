@@ -0,0 +1,236 @@
+package go_vcard
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+a RELATED property,URI is a reference to the related entity,e.g. a UID like
+urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6,Text is set instead for
+VALUE=text
+ */
+type Related struct {
+	*Property
+
+	URI string
+	Text string
+	Types []string //lower case,e.g. RelatedSpouse,RelatedColleague
+	Pref int
+}
+
+func newRelated(p *Property) *Related {
+	r := &Related{Property:p}
+	value := joinValue(p.Value)
+	if _,typ := findParam(p.Params,ParamValue);strings.EqualFold(typ,"text"){
+		r.Text = value
+	}else{
+		r.URI = value
+	}
+	r.Types,r.Pref = typesAndPref(p)
+	return r
+}
+
+func (r *Related) HasType(typ string) bool {
+	return hasType(r.Types,typ)
+}
+
+func (r *Related) property() *Property {
+	if r.Property == nil{
+		r.Property = &Property{Name:PropRelated}
+	}
+	p := r.Property
+	if k,_ := findParam(p.Params,ParamValue);k != ""{
+		delete(p.Params,k)
+	}
+	if r.URI == "" && r.Text != ""{
+		p.Value = [][]string{{r.Text}}
+		p.SetParam(ParamValue,"text")
+	}else{
+		p.Value = [][]string{{r.URI}}
+	}
+	setTypesAndPref(p,r.Types,r.Pref)
+	return p
+}
+
+func (c Card) Related() []*Related {
+	props := c[PropRelated]
+	if props == nil{
+		return nil
+	}
+	related := make([]*Related,len(props))
+	for i,p := range props{
+		related[i] = newRelated(p)
+	}
+	return related
+}
+
+func (c Card) AddRelated(r *Related)  {
+	c.Add(PropRelated,r.property())
+}
+
+/*
+return the URIs of MEMBER,e.g. urn:uuid:... or mailto:...
+ */
+func (c Card) Members() []string {
+	var members []string
+	for _,p := range c[PropMember]{
+		members = append(members,joinValue(p.Value))
+	}
+	return members
+}
+
+func (c Card) AddMember(uri string)  {
+	c.Add(PropMember,&Property{Name:PropMember,Value:[][]string{{uri}}})
+}
+
+/*
+a RELATED or MEMBER reference of an address book.To is nil if the URI is not
+the UID of a card in the address book
+ */
+type Relation struct {
+	From Card
+	To Card
+	URI string
+	Types []string //types of RELATED,nil for MEMBER
+	Member bool
+}
+
+/*
+a dangling reference is a urn: URI(e.g. urn:uuid:) that is not the UID of any
+card,other URIs like mailto: or http: are external and not dangling
+ */
+func (r *Relation) Dangling() bool {
+	return r.To == nil && isURN(r.URI)
+}
+
+func isURN(uri string) bool {
+	return len(uri) >= 4 && strings.EqualFold(uri[:4],"urn:")
+}
+
+/*
+a set of vCard 4.0 cards that reference each other by UID.convert vCard 3.0
+cards with Card.ToV4 before,X-ADDRESSBOOKSERVER-MEMBER become MEMBER
+ */
+type AddressBook struct {
+	cards []Card
+	byUID map[string]Card
+}
+
+func NewAddressBook(cards []Card) *AddressBook {
+	ab := &AddressBook{cards:cards,byUID:make(map[string]Card)}
+	for _,c := range cards{
+		if p := c.Get(PropUid);p != nil{
+			if uid := uidKey(p.GetValueFirstText());uid != ""{
+				ab.byUID[uid] = c
+			}
+		}
+	}
+	return ab
+}
+
+/*
+UIDs are compared without "urn:uuid:" and case
+ */
+func uidKey(uid string) string {
+	uid = strings.ToLower(strings.TrimSpace(uid))
+	return strings.TrimPrefix(uid,"urn:uuid:")
+}
+
+/*
+return the card of a UID,nil if not found
+ */
+func (ab *AddressBook) Card(uid string) Card {
+	return ab.byUID[uidKey(uid)]
+}
+
+/*
+return all RELATED and MEMBER references of the cards,in the order of cards
+ */
+func (ab *AddressBook) Relations() []*Relation {
+	var rels []*Relation
+	for _,c := range ab.cards{
+		for _,r := range c.Related(){
+			if r.URI == ""{
+				continue
+			}
+			rels = append(rels,&Relation{From:c,To:ab.Card(r.URI),URI:r.URI,Types:r.Types})
+		}
+		for _,uri := range c.Members(){
+			rels = append(rels,&Relation{From:c,To:ab.Card(uri),URI:uri,Member:true})
+		}
+	}
+	return rels
+}
+
+/*
+return the references to a card that is not in the address book
+ */
+func (ab *AddressBook) Dangling() []*Relation {
+	var dangling []*Relation
+	for _,r := range ab.Relations(){
+		if r.Dangling(){
+			dangling = append(dangling,r)
+		}
+	}
+	return dangling
+}
+
+/*
+a member of a group,Card is nil for an external member,e.g. mailto:
+ */
+type Member struct {
+	Card Card
+	URI string
+}
+
+/*
+return the direct members of a KIND:group card,dangling members are skipped
+ */
+func (ab *AddressBook) Members(group Card) ([]*Member,error) {
+	if group.Kind() != KindGroup{
+		return nil,fmt.Errorf("vcard:card is not a group,KIND is %q",group.Kind())
+	}
+	var members []*Member
+	for _,uri := range group.Members(){
+		m := &Member{Card:ab.Card(uri),URI:uri}
+		if m.Card == nil && isURN(uri){
+			continue
+		}
+		members = append(members,m)
+	}
+	return members,nil
+}
+
+/*
+return the members of a group with nested groups replaced by their members,
+each member is returned once and cycles are ignored
+ */
+func (ab *AddressBook) ExpandMembers(group Card) ([]*Member,error) {
+	if group.Kind() != KindGroup{
+		return nil,fmt.Errorf("vcard:card is not a group,KIND is %q",group.Kind())
+	}
+	var members []*Member
+	seen := make(map[string]bool)
+	var expand func(g Card)
+	expand = func(g Card) {
+		ms,_ := ab.Members(g)
+		for _,m := range ms{
+			key := uidKey(m.URI)
+			if seen[key]{
+				continue
+			}
+			seen[key] = true
+			if m.Card != nil && m.Card.Kind() == KindGroup{
+				expand(m.Card)
+				continue
+			}
+			members = append(members,m)
+		}
+	}
+	if p := group.Get(PropUid);p != nil{
+		seen[uidKey(p.GetValueFirstText())] = true
+	}
+	expand(group)
+	return members,nil
+}
@@ -0,0 +1,129 @@
+package go_vcard
+
+import (
+	"strings"
+	"testing"
+)
+
+func decodeTestCards(s string) []Card {
+	var cards []Card
+	dec := NewDecoder(strings.NewReader(s))
+	for {
+		c, err := dec.Decode()
+		if err != nil {
+			break
+		}
+		cards = append(cards, c)
+	}
+	return cards
+}
+
+func TestAddressBook(t *testing.T) {
+	cards := decodeTestCards("BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Jane\r\n"+
+		"UID:urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af\r\n"+
+		"RELATED;TYPE=spouse:urn:uuid:F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6\r\n"+
+		"RELATED;TYPE=co-worker;VALUE=text:Please contact my assistant\r\n"+
+		"RELATED;TYPE=friend:urn:uuid:00000000-0000-0000-0000-000000000000\r\n"+
+		"END:VCARD\r\n"+
+		"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Joe\r\n"+
+		"UID:urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6\r\n"+
+		"END:VCARD\r\n"+
+		"BEGIN:VCARD\r\nVERSION:4.0\r\nKIND:group\r\nFN:Team\r\n"+
+		"UID:urn:uuid:aaaaaaaa-0000-0000-0000-000000000001\r\n"+
+		"MEMBER:urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af\r\n"+
+		"MEMBER:urn:uuid:aaaaaaaa-0000-0000-0000-000000000002\r\n"+
+		"MEMBER:mailto:guest@example.com\r\n"+
+		"MEMBER:urn:uuid:11111111-0000-0000-0000-000000000000\r\n"+
+		"END:VCARD\r\n"+
+		"BEGIN:VCARD\r\nVERSION:4.0\r\nKIND:group\r\nFN:Sub team\r\n"+
+		"UID:urn:uuid:aaaaaaaa-0000-0000-0000-000000000002\r\n"+
+		"MEMBER:urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6\r\n"+
+		"MEMBER:urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af\r\n"+
+		"MEMBER:urn:uuid:aaaaaaaa-0000-0000-0000-000000000001\r\n"+
+		"END:VCARD\r\n")
+	if len(cards) != 4 {
+		t.Fatalf("Expected 4 cards but got %d", len(cards))
+	}
+	jane, joe, team := cards[0], cards[1], cards[2]
+
+	related := jane.Related()
+	if len(related) != 3 || !related[0].HasType(RelatedSpouse) || related[1].Text != "Please contact my assistant" || related[1].URI != "" {
+		t.Errorf("Unexpected related %+v", related)
+	}
+
+	ab := NewAddressBook(cards)
+	if c := ab.Card("F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6"); c == nil || c.Get(PropFN).GetValueFirstText() != "Joe" {
+		t.Error("Expected to find Joe by UID")
+	}
+
+	rels := ab.Relations()
+	if len(rels) != 9 {
+		t.Errorf("Expected 9 relations but got %d", len(rels))
+	}
+	if r := rels[0]; r.To == nil || r.To.Get(PropFN).GetValueFirstText() != "Joe" || r.Member || r.Types[0] != RelatedSpouse {
+		t.Errorf("Unexpected spouse relation %+v", r)
+	}
+	dangling := ab.Dangling()
+	if len(dangling) != 2 || dangling[0].URI != "urn:uuid:00000000-0000-0000-0000-000000000000" || !dangling[1].Member {
+		t.Errorf("Unexpected dangling relations %+v", dangling)
+	}
+
+	members, err := ab.Members(team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 3 || members[2].Card != nil || members[2].URI != "mailto:guest@example.com" {
+		t.Errorf("Unexpected members %+v", members)
+	}
+
+	flat, err := ab.ExpandMembers(team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range flat {
+		if m.Card != nil {
+			names = append(names, m.Card.Get(PropFN).GetValueFirstText())
+		} else {
+			names = append(names, m.URI)
+		}
+	}
+	if strings.Join(names, ",") != "Jane,Joe,mailto:guest@example.com" {
+		t.Errorf("Unexpected expanded members %v", names)
+	}
+
+	if _, err := ab.Members(joe); err == nil {
+		t.Error("Expected an error for the members of an individual")
+	}
+
+	joe.AddRelated(&Related{URI: "urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af", Types: []string{RelatedSpouse}})
+	joe.AddMember("urn:uuid:x")
+	if r := joe.Related(); len(r) != 1 || r[0].Name != PropRelated || !r[0].HasType(RelatedSpouse) {
+		t.Errorf("Unexpected related after AddRelated %+v", r)
+	}
+	if m := joe.Members(); len(m) != 1 || m[0] != "urn:uuid:x" {
+		t.Errorf("Unexpected members after AddMember %v", m)
+	}
+}
+
+func TestAddressBook_v3Group(t *testing.T) {
+	cards := decodeTestCards("BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane\r\nN:;Jane;;;\r\n"+
+		"UID:03a0e51f-d1aa-4385-8a53-e29025acd8af\r\n"+
+		"END:VCARD\r\n"+
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Team\r\nN:Team;;;;\r\n"+
+		"X-ADDRESSBOOKSERVER-KIND:group\r\n"+
+		"X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:03a0e51f-d1aa-4385-8a53-e29025acd8af\r\n"+
+		"END:VCARD\r\n")
+	for _, c := range cards {
+		if _, err := c.ToV4(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	members, err := NewAddressBook(cards).Members(cards[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0].Card == nil || members[0].Card.Get(PropFN).GetValueFirstText() != "Jane" {
+		t.Errorf("Unexpected members %+v", members)
+	}
+}
@@ -0,0 +1,262 @@
+package go_vcard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+properties with at most one instance,they have no PID and the remote one
+replace the local one
+ */
+var singleProps = map[string]bool{
+	PropKind: true,
+	PropN: true,
+	PropBday: true,
+	PropAnniversary: true,
+	PropGender: true,
+	PropProdid: true,
+	PropRev: true,
+	PropUid: true,
+}
+
+/*
+a global PID:the local id and the URI of its source in CLIENTPIDMAP
+ */
+type pidKey struct {
+	local int
+	uri string
+}
+
+/*
+parse the CLIENTPIDMAP of a card,source id to URI
+ */
+func clientPidMap(c Card) (map[int]string,error) {
+	m := make(map[int]string)
+	for _,p := range c[PropClientPidmap]{
+		if len(p.Value) < 2{
+			return nil,fmt.Errorf("vcard:invalid CLIENTPIDMAP %q",joinValue(p.Value))
+		}
+		id,err := strconv.Atoi(component(p.Value,0))
+		if err != nil || id < 1{
+			return nil,fmt.Errorf("vcard:invalid CLIENTPIDMAP source id %q",component(p.Value,0))
+		}
+		m[id] = joinValue(p.Value[1:])
+	}
+	return m,nil
+}
+
+/*
+return the global PIDs of a property,PIDs without source or with an unknown
+source are ignored
+ */
+func propPids(p *Property,pidmap map[int]string) []pidKey {
+	k,_ := findParam(p.Params,ParamPid)
+	if k == ""{
+		return nil
+	}
+	var keys []pidKey
+	for _,pid := range p.Params[k]{
+		parts := strings.SplitN(strings.TrimSpace(pid),".",2)
+		if len(parts) != 2{
+			continue
+		}
+		local,err1 := strconv.Atoi(parts[0])
+		source,err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil || pidmap[source] == ""{
+			continue
+		}
+		keys = append(keys,pidKey{local,pidmap[source]})
+	}
+	return keys
+}
+
+/*
+the synchronization state of the merged card
+ */
+type pidMerger struct {
+	c Card
+	pidmap map[int]string
+	ids map[string]int //URI to source id
+}
+
+/*
+return the source id of uri in the merged card,add a CLIENTPIDMAP if needed
+ */
+func (m *pidMerger) sourceID(uri string) int {
+	if id,ok := m.ids[uri];ok{
+		return id
+	}
+	id := 1
+	for i := range m.pidmap{
+		if i >= id{
+			id = i+1
+		}
+	}
+	m.pidmap[id] = uri
+	m.ids[uri] = id
+	m.c.Add(PropClientPidmap,&Property{Name:PropClientPidmap,Value:[][]string{{strconv.Itoa(id)},{uri}}})
+	return id
+}
+
+/*
+set PID param of p to keys
+ */
+func (m *pidMerger) setPids(p *Property,keys []pidKey)  {
+	if k,_ := findParam(p.Params,ParamPid);k != ""{
+		delete(p.Params,k)
+	}
+	seen := make(map[pidKey]bool)
+	for _,key := range keys{
+		if seen[key]{
+			continue
+		}
+		seen[key] = true
+		p.AddParam(ParamPid,strconv.Itoa(key.local)+"."+strconv.Itoa(m.sourceID(key.uri)))
+	}
+}
+
+/*
+return a new PID of uri for a property of name k
+ */
+func (m *pidMerger) newPid(k,uri string) pidKey {
+	local := 0
+	for _,p := range m.c[k]{
+		for _,key := range propPids(p,m.pidmap){
+			if key.uri == uri && key.local > local{
+				local = key.local
+			}
+		}
+	}
+	return pidKey{local+1,uri}
+}
+
+func hasPid(keys []pidKey,key pidKey) bool {
+	for _,k := range keys{
+		if k == key{
+			return true
+		}
+	}
+	return false
+}
+
+/*
+merge the remote card of the client clientURI into the local card,as the
+synchronization of RFC 6350 section 7.neither card is modified.
+
+property instances are matched by their global PID(local id and the URI of
+the source in CLIENTPIDMAP),then by equal value.a matched instance take the
+value and params of the remote one,an unmatched remote instance is added with
+a new PID of clientURI.a local instance with a PID of clientURI missing in
+remote has been deleted by the client and is removed.the remote instance of
+KIND,N,BDAY,ANNIVERSARY,GENDER,PRODID,REV and UID replace the local one
+ */
+func Merge(local,remote Card,clientURI string) (Card,error) {
+	if clientURI == ""{
+		return nil,fmt.Errorf("vcard:empty client URI")
+	}
+	localMap,err := clientPidMap(local)
+	if err != nil{
+		return nil,err
+	}
+	remoteMap,err := clientPidMap(remote)
+	if err != nil{
+		return nil,err
+	}
+	m := &pidMerger{c:make(Card,len(local)),pidmap:localMap,ids:make(map[string]int)}
+	for k,ps := range local{
+		for _,p := range ps{
+			cp := p.copy()
+			m.c.Add(k,&cp)
+		}
+	}
+	for id,uri := range localMap{
+		m.ids[uri] = id
+	}
+
+	var keys []string
+	for k := range remote{
+		keys = append(keys,k)
+	}
+	sort.Strings(keys)
+	seen := make(map[*Property]bool)
+	for _,k := range keys{
+		switch strings.ToUpper(k) {
+		case PropVersion,PropClientPidmap,"BEGIN","END":
+			continue
+		}
+		if singleProps[strings.ToUpper(k)]{
+			m.c[k] = nil
+			for _,rp := range remote[k]{
+				cp := rp.copy()
+				m.c.Add(k,&cp)
+				seen[&cp] = true
+			}
+			continue
+		}
+		for _,rp := range remote[k]{
+			rkeys := propPids(rp,remoteMap)
+			var target *Property
+			for _,p := range m.c[k]{
+				for _,key := range propPids(p,m.pidmap){
+					if hasPid(rkeys,key){
+						target = p
+						break
+					}
+				}
+				if target != nil{
+					break
+				}
+			}
+			if target == nil{
+				value := joinValue(rp.Value)
+				for _,p := range m.c[k]{
+					if p.Group == rp.Group && joinValue(p.Value) == value{
+						target = p
+						break
+					}
+				}
+			}
+			cp := rp.copy()
+			if target == nil{
+				if len(rkeys) == 0{
+					rkeys = []pidKey{m.newPid(k,clientURI)}
+				}
+				m.setPids(&cp,rkeys)
+				m.c.Add(k,&cp)
+				seen[m.c[k][len(m.c[k])-1]] = true
+				continue
+			}
+			//the remote instance is the latest edit
+			m.setPids(&cp,append(propPids(target,m.pidmap),rkeys...))
+			*target = cp
+			seen[target] = true
+		}
+	}
+
+	//deleted by the client
+	for k,ps := range m.c{
+		kept := ps[:0]
+		for _,p := range ps{
+			deleted := false
+			if !seen[p]{
+				for _,key := range propPids(p,m.pidmap){
+					if key.uri == clientURI{
+						deleted = true
+					}
+				}
+			}
+			if !deleted{
+				kept = append(kept,p)
+			}
+		}
+		if len(kept) == 0{
+			delete(m.c,k)
+		}else{
+			m.c[k] = kept
+		}
+	}
+	return m.c,nil
+}
@@ -0,0 +1,94 @@
+package go_vcard
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	testClientA = "urn:uuid:3df403f4-5924-4bb7-b077-3c711d9eb34b"
+	testClientB = "urn:uuid:d89c9c7a-2e1b-4832-82de-7e992d95faa5"
+)
+
+func emailValues(c Card) []string {
+	var emails []string
+	for _, p := range c[PropEmail] {
+		emails = append(emails, p.GetValueFirstText()+" "+strings.Join(p.Params[ParamPid], ","))
+	}
+	sort.Strings(emails)
+	return emails
+}
+
+func TestMerge(t *testing.T) {
+	local := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"J. Doe"}}, Params: map[string][]string{ParamPid: {"1.1"}}}},
+		PropN:       {{Name: PropN, Value: [][]string{{"Doe"}, {"J."}, {""}, {""}, {""}}}},
+		PropEmail: {
+			{Name: PropEmail, Value: [][]string{{"jdoe@example.com"}}, Params: map[string][]string{ParamPid: {"1.1"}}},
+			{Name: PropEmail, Value: [][]string{{"john@example.com"}}, Params: map[string][]string{ParamPid: {"2.1"}}},
+		},
+		PropTel:          {{Name: PropTel, Value: [][]string{{"+1 555 0100"}}, Params: map[string][]string{ParamPid: {"1.1"}}}},
+		PropClientPidmap: {{Name: PropClientPidmap, Value: [][]string{{"1"}, {testClientA}}}},
+	}
+	// client B numbers its sources differently
+	remote := Card{
+		PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}},
+		PropFN:      {{Name: PropFN, Value: [][]string{{"J. Doe"}}, Params: map[string][]string{ParamPid: {"1.2"}}}},
+		PropN:       {{Name: PropN, Value: [][]string{{"Doe"}, {"John"}, {""}, {""}, {""}}}},
+		PropEmail: {
+			{Name: PropEmail, Value: [][]string{{"jdoe@example.org"}}, Params: map[string][]string{ParamPid: {"1.2"}}},
+			{Name: PropEmail, Value: [][]string{{"john@example.com"}}},
+			{Name: PropEmail, Value: [][]string{{"new@example.com"}}},
+		},
+		PropClientPidmap: {{Name: PropClientPidmap, Value: [][]string{{"2"}, {testClientA}}}},
+	}
+
+	merged, err := Merge(local, remote, testClientB)
+	if err != nil {
+		t.Fatal("Expected no error when merging, got:", err)
+	}
+	expected := []string{"jdoe@example.org 1.1", "john@example.com 2.1", "new@example.com 1.2"}
+	if emails := emailValues(merged); !reflect.DeepEqual(emails, expected) {
+		t.Errorf("Expected emails %v but got %v", expected, emails)
+	}
+	if len(merged[PropFN]) != 1 || len(merged[PropTel]) != 1 {
+		t.Errorf("Expected FN and TEL to be kept once, got %v and %v", merged[PropFN], merged[PropTel])
+	}
+	if n := merged.Name(); n.GivenName != "John" {
+		t.Errorf("Expected N from remote but got %+v", n)
+	}
+	pidmap, _ := clientPidMap(merged)
+	if !reflect.DeepEqual(pidmap, map[int]string{1: testClientA, 2: testClientB}) {
+		t.Errorf("Unexpected CLIENTPIDMAP %v", pidmap)
+	}
+	if emails := emailValues(local); emails[0] != "jdoe@example.com 1.1" || len(emails) != 2 {
+		t.Errorf("Expected local not to be modified but got %v", emails)
+	}
+
+	// client B deletes the email it added
+	remote = Card{
+		PropEmail: {
+			{Name: PropEmail, Value: [][]string{{"jdoe@example.org"}}, Params: map[string][]string{ParamPid: {"1.1"}}},
+		},
+		PropClientPidmap: {{Name: PropClientPidmap, Value: [][]string{{"1"}, {testClientA}}}},
+	}
+	merged, err = Merge(merged, remote, testClientB)
+	if err != nil {
+		t.Fatal("Expected no error when merging, got:", err)
+	}
+	expected = []string{"jdoe@example.org 1.1", "john@example.com 2.1"}
+	if emails := emailValues(merged); !reflect.DeepEqual(emails, expected) {
+		t.Errorf("Expected emails %v after deletion but got %v", expected, emails)
+	}
+
+	if _, err := Merge(local, remote, ""); err == nil {
+		t.Error("Expected an error for an empty client URI")
+	}
+	remote[PropClientPidmap] = []*Property{{Name: PropClientPidmap, Value: [][]string{{"x"}, {testClientA}}}}
+	if _, err := Merge(local, remote, testClientB); err == nil {
+		t.Error("Expected an error for an invalid CLIENTPIDMAP")
+	}
+}
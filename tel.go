@@ -0,0 +1,295 @@
+package go_vcard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+a TEL property,the value is either free text or a tel: URI(RFC 3966) with
+VALUE=uri,e.g. tel:+1-555-555-5555;ext=102
+ */
+type Telephone struct {
+	*Property
+
+	Number string
+	Extension string
+	Types []string //lower case,e.g. TelCell,TelFax
+	Pref int //1 is the most preferred,0 if not set
+	URI bool //write as a tel: URI
+}
+
+func newTelephone(p *Property) *Telephone {
+	t := &Telephone{Property:p}
+	raw := joinValue(p.Value)
+	if len(raw) >= 4 && strings.EqualFold(raw[:4],"tel:"){
+		t.URI = true
+		parts := strings.Split(raw[4:],";")
+		t.Number = parts[0]
+		for _,param := range parts[1:]{
+			if kv := strings.SplitN(param,"=",2);len(kv) == 2 && strings.EqualFold(kv[0],"ext"){
+				t.Extension = kv[1]
+			}
+		}
+	}else{
+		t.Number,t.Extension = splitExtension(raw)
+	}
+	t.Types,t.Pref = typesAndPref(p)
+	return t
+}
+
+/*
+split a trailing extension from a text number,e.g. "+1 555 0100 ext. 102"
+ */
+func splitExtension(s string) (number,ext string) {
+	lower := strings.ToLower(s)
+	for _,marker := range []string{"extension","ext.","ext","x","#"}{
+		i := strings.LastIndex(lower,marker)
+		if i <= 0{
+			continue
+		}
+		rest := strings.TrimSpace(s[i+len(marker):])
+		if rest != "" && isDigits(rest){
+			return strings.TrimSpace(s[:i]),rest
+		}
+	}
+	return s,""
+}
+
+func (t *Telephone) HasType(typ string) bool {
+	return hasType(t.Types,typ)
+}
+
+func hasType(types []string,typ string) bool {
+	for _,t := range types{
+		if strings.EqualFold(t,typ){
+			return true
+		}
+	}
+	return false
+}
+
+/*
+the tel: URI of the number,visual separators other than '-','.','(',')'
+are replaced by '-'
+ */
+func (t *Telephone) TelURI() string {
+	number := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\t'{
+			return '-'
+		}
+		return r
+	},strings.TrimSpace(t.Number))
+	uri := "tel:"+number
+	if t.Extension != ""{
+		uri += ";ext="+t.Extension
+	}
+	return uri
+}
+
+/*
+the E.164 form of the number,see NormalizeE164
+ */
+func (t *Telephone) E164(region string) (string,error) {
+	return NormalizeE164(t.Number,region)
+}
+
+func (t *Telephone) property() *Property {
+	if t.Property == nil{
+		t.Property = new(Property)
+	}
+	p := t.Property
+	if k,_ := findParam(p.Params,ParamValue);k != ""{
+		delete(p.Params,k)
+	}
+	if t.URI{
+		p.Value = parseValues(t.TelURI())
+		p.SetParam(ParamValue,"uri")
+	}else{
+		text := t.Number
+		if t.Extension != ""{
+			text += " ext. "+t.Extension
+		}
+		p.Value = [][]string{{text}}
+	}
+	setTypesAndPref(p,t.Types,t.Pref)
+	return p
+}
+
+/*
+return the TYPE param in lower case without "pref",and the preference from
+PREF param or the "pref" type
+ */
+func typesAndPref(p *Property) (types []string,pref int) {
+	for _,typ := range p.GetParamTypeList(){
+		if typ == "pref"{
+			//for Apple contact,add "pref" to TYPE param
+			pref = 1
+			continue
+		}
+		types = append(types,typ)
+	}
+	if v := p.GetFirstParamVal(ParamPref);v != ""{
+		pref,_ = strconv.Atoi(v)
+	}
+	return types,pref
+}
+
+func setTypesAndPref(p *Property,types []string,pref int)  {
+	if k,_ := findParam(p.Params,ParamType);k != ""{
+		delete(p.Params,k)
+	}
+	for _,typ := range types{
+		p.AddParam(ParamType,typ)
+	}
+	if k,_ := findParam(p.Params,ParamPref);k != ""{
+		delete(p.Params,k)
+	}
+	if pref > 0{
+		p.SetParam(ParamPref,strconv.Itoa(pref))
+	}
+}
+
+func (c Card) Telephones() []*Telephone {
+	tels := c[PropTel]
+	if tels == nil{
+		return nil
+	}
+	telephones := make([]*Telephone,len(tels))
+	for i,tel := range tels{
+		telephones[i] = newTelephone(tel)
+	}
+	return telephones
+}
+
+/*
+return the preferred telephone
+ */
+func (c Card) Telephone() *Telephone {
+	tel := c.Pref(PropTel)
+	if tel == nil{
+		return nil
+	}
+	return newTelephone(tel)
+}
+
+func (c Card) AddTelephone(t *Telephone)  {
+	p := t.property()
+	if p.Name == ""{
+		p.Name = PropTel
+	}
+	c.Add(PropTel,p)
+}
+
+/*
+calling code,trunk prefix and international prefix of a region
+ */
+type dialingPlan struct {
+	code string
+	trunk string
+	intl string
+}
+
+/*
+dialing plans by ISO 3166-1 alpha-2 region
+ */
+var dialingPlans = map[string]dialingPlan{
+	"US": {"1","1","011"},
+	"CA": {"1","1","011"},
+	"PR": {"1","1","011"},
+	"GB": {"44","0","00"},
+	"IE": {"353","0","00"},
+	"DE": {"49","0","00"},
+	"AT": {"43","0","00"},
+	"CH": {"41","0","00"},
+	"FR": {"33","0","00"},
+	"BE": {"32","0","00"},
+	"NL": {"31","0","00"},
+	"LU": {"352","","00"},
+	"IT": {"39","","00"},
+	"ES": {"34","","00"},
+	"PT": {"351","","00"},
+	"DK": {"45","","00"},
+	"NO": {"47","","00"},
+	"SE": {"46","0","00"},
+	"FI": {"358","0","00"},
+	"PL": {"48","","00"},
+	"CZ": {"420","","00"},
+	"GR": {"30","","00"},
+	"RU": {"7","8","810"},
+	"TR": {"90","0","00"},
+	"IL": {"972","0","00"},
+	"IN": {"91","0","00"},
+	"CN": {"86","0","00"},
+	"HK": {"852","","001"},
+	"TW": {"886","0","002"},
+	"JP": {"81","0","010"},
+	"KR": {"82","0","001"},
+	"SG": {"65","","000"},
+	"AU": {"61","0","0011"},
+	"NZ": {"64","0","00"},
+	"BR": {"55","0","00"},
+	"MX": {"52","","00"},
+	"AR": {"54","0","00"},
+	"ZA": {"27","0","00"},
+}
+
+/*
+convert a free-form number to E.164,e.g. "(020) 1234 5678" in region "GB"
+to +442012345678.a number starting with '+' or the international prefix of the
+region is already international,region may be empty for them.letters are
+mapped to digits as on a phone keypad
+ */
+func NormalizeE164(number,region string) (string,error) {
+	number,_ = splitExtension(strings.TrimSpace(number))
+	if len(number) >= 4 && strings.EqualFold(number[:4],"tel:"){
+		number = strings.SplitN(number[4:],";",2)[0]
+	}
+	var digits strings.Builder
+	plus := false
+	for i,r := range number{
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			digits.WriteByte(keypadDigit(r))
+		case r == '+' && digits.Len() == 0 && !plus:
+			plus = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		default:
+			return "",fmt.Errorf("vcard:invalid character %q at %d in phone number %q",r,i,number)
+		}
+	}
+	d := digits.String()
+	if !plus{
+		plan,ok := dialingPlans[strings.ToUpper(region)]
+		switch {
+		case ok && strings.HasPrefix(d,plan.intl):
+			d = d[len(plan.intl):]
+		case ok:
+			if plan.trunk != "" && strings.HasPrefix(d,plan.trunk) &&
+				//a NANP number without trunk prefix has 10 digits
+				(plan.code != "1" || len(d) == 11){
+				d = d[len(plan.trunk):]
+			}
+			d = plan.code+d
+		case region == "":
+			return "",fmt.Errorf("vcard:phone number %q is not international and no region is given",number)
+		default:
+			return "",fmt.Errorf("vcard:unknown region %q",region)
+		}
+	}
+	if len(d) < 8 || len(d) > 15 || d[0] == '0'{
+		return "",fmt.Errorf("vcard:phone number %q is not a valid E.164 number",number)
+	}
+	return "+"+d,nil
+}
+
+func keypadDigit(r rune) byte {
+	const keypad = "22233344455566677778889999"
+	if r >= 'a'{
+		r -= 'a'-'A'
+	}
+	return keypad[r-'A']
+}
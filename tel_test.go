@@ -0,0 +1,87 @@
+package go_vcard
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCard_Telephones(t *testing.T) {
+	card, err := NewDecoder(strings.NewReader("BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"TEL;VALUE=uri;TYPE=\"voice,home\";PREF=2:tel:+1-555-555-5555;ext=102\r\n" +
+		"TEL;TYPE=cell,pref:+44 20 1234 5678 x9\r\n" +
+		"END:VCARD\r\n")).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tels := card.Telephones()
+	if len(tels) != 2 {
+		t.Fatalf("Expected 2 telephones but got %d", len(tels))
+	}
+	if tel := tels[0]; !tel.URI || tel.Number != "+1-555-555-5555" || tel.Extension != "102" || tel.Pref != 2 ||
+		!reflect.DeepEqual(tel.Types, []string{TelVoice, "home"}) {
+		t.Errorf("Unexpected uri telephone %+v", tel)
+	}
+	if tel := tels[1]; tel.URI || tel.Number != "+44 20 1234 5678" || tel.Extension != "9" || tel.Pref != 1 ||
+		!tel.HasType(TelCell) || tel.HasType("pref") {
+		t.Errorf("Unexpected text telephone %+v", tel)
+	}
+	if tel := card.Telephone(); tel.Number != "+1-555-555-5555" {
+		t.Errorf("Expected the preferred telephone to have PREF=2 but got %+v", tel)
+	}
+
+	added := &Telephone{Number: "+1 555 0100", Extension: "7", Types: []string{TelFax, "work"}, Pref: 1, URI: true}
+	card = Card{PropVersion: {{Name: PropVersion, Value: [][]string{{"4.0"}}}}}
+	card.AddTelephone(added)
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "TEL;TYPE=fax,work;PREF=1;VALUE=uri:tel:+1-555-0100;ext=7\r\n"; !strings.Contains(b.String(), expected) {
+		t.Errorf("Expected %q in\n%s", expected, b.String())
+	}
+	if tel := card.Telephone(); tel.Number != "+1-555-0100" || tel.Extension != "7" || !tel.URI {
+		t.Errorf("Unexpected telephone after round trip %+v", tel)
+	}
+
+	added.URI = false
+	card = make(Card)
+	card.AddTelephone(added)
+	if p := card.Get(PropTel); p.GetValueFirstText() != "+1 555 0100 ext. 7" || p.GetFirstParamVal(ParamValue) != "" {
+		t.Errorf("Unexpected text TEL %+v", p)
+	}
+}
+
+func TestNormalizeE164(t *testing.T) {
+	tests := []struct {
+		number, region, expected string
+	}{
+		{"+44 20 1234 5678", "", "+442012345678"},
+		{"(020) 1234 5678", "GB", "+442012345678"},
+		{"00 44 20 1234 5678", "DE", "+442012345678"},
+		{"(555) 555-5555", "US", "+15555555555"},
+		{"1-555-555-5555", "us", "+15555555555"},
+		{"011 33 1 23 45 67 89", "US", "+33123456789"},
+		{"06 12 34 56 78", "FR", "+33612345678"},
+		{"06 1234 5678", "IT", "+390612345678"},
+		{"1-800-FLOWERS", "US", "+18003569377"},
+		{"tel:+1-555-555-5555;ext=102", "", "+15555555555"},
+		{"+1 555 555 5555 ext. 12", "", "+15555555555"},
+	}
+	for _, test := range tests {
+		if got, err := NormalizeE164(test.number, test.region); err != nil {
+			t.Errorf("NormalizeE164(%q, %q): unexpected error: %v", test.number, test.region, err)
+		} else if got != test.expected {
+			t.Errorf("NormalizeE164(%q, %q) = %q, want %q", test.number, test.region, got, test.expected)
+		}
+	}
+
+	for _, test := range [][2]string{{"020 1234 5678", ""}, {"020 1234 5678", "XX"}, {"+44 20 1234 5678*", ""}, {"+123", ""}} {
+		if _, err := NormalizeE164(test[0], test[1]); err == nil {
+			t.Errorf("NormalizeE164(%q, %q): expected an error", test[0], test[1])
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package go_vcard
 
 import (
-	"fmt"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +24,36 @@ func (c Card) Get(k string) *Property {
 	}
 	return props[0]
 }
+/*
+return all properties in the order they are encoded:VERSION first,then in
+their original order for a lossless decoded card,else sorted by name
+ */
+func (c Card) Properties() []*Property {
+	var keys []string
+	for k := range c{
+		keys = append(keys,k)
+	}
+	sort.Strings(keys)
+	var version,props []*Property
+	for _,k := range keys{
+		for _,p := range c[k]{
+			if p.Name == ""{
+				//e.g. added by SetValue,the key is its name
+				q := *p
+				q.Name = k
+				p = &q
+			}
+			if strings.EqualFold(k,PropVersion){
+				version = append(version,p)
+			}else{
+				props = append(props,p)
+			}
+		}
+	}
+	sortBySource(props)
+	return append(version,props...)
+}
+
 /*
 Add property
  */
@@ -211,33 +244,41 @@ func (c Card) Revision() (time.Time,error) {
 	if rev == nil{
 		return time.Time{},nil
 	}
-	return time.Parse(timestampLayout,rev[0][0])
+	if t,err := time.Parse(timestampLayout,rev[0][0]);err == nil{
+		return t,nil
+	}
+	//vCard 3.0 may use the extended form or a date only
+	d,err := ParseDateAndOrTime(rev[0][0])
+	if err != nil{
+		return time.Time{},err
+	}
+	return d.Time(time.UTC)
 }
 
 func (c Card) SetRevision(t time.Time)  {
 	c.SetValue(PropRev,[][]string{{t.Format(timestampLayout)}})
 }
 
-func (c Card) ToV4() error {
-	vers := c.Value(PropVersion)
-	if len(vers) < 1{
-		return fmt.Errorf("vcard:VCARD must have version infomation")
-	}
-	ver := vers[0][0]
-	if strings.HasPrefix(ver,"4."){
-		return nil
+/*
+return the canonical encoding of the card:stable param order and upper case
+names,suitable for comparison and content addressing
+ */
+func (c Card) CanonicalBytes() ([]byte,error) {
+	var buf bytes.Buffer
+	if err := NewEncoderWithOptions(&buf,EncodeOptions{Canonical:true}).Encode(c);err != nil{
+		return nil,err
 	}
-	c.SetValue(PropVersion,[][]string{{"4.0"}})
-	for k,props := range c{
-		if strings.EqualFold(k,PropVersion){
-			continue
-		}
-		for _,p := range props{
-			if p.IsHasType("pref"){
-				delete(p.Params,"pref")
-				p.SetParam("pref","1")
-			}
-		}
+	return buf.Bytes(),nil
+}
+
+/*
+return the hex SHA-256 of the canonical encoding,e.g. as an ETag
+ */
+func (c Card) CanonicalHash() (string,error) {
+	b,err := c.CanonicalBytes()
+	if err != nil{
+		return "",err
 	}
-	return nil
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]),nil
 }
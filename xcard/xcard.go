@@ -0,0 +1,542 @@
+//https://tools.ietf.org/html/rfc6351
+//xCard,the XML format for vCard
+package xcard
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	vcard "github.com/ScottAI/go-vcard"
+)
+
+//namespace of xCard elements
+const Namespace = "urn:ietf:params:xml:ns:vcard-4.0"
+
+//namespace of the xml prefix,e.g. xml:lang
+const xmlSpace = "http://www.w3.org/XML/1998/namespace"
+
+/*
+component elements of the structured properties
+ */
+var components = map[string][]string{
+	vcard.PropN: {"surname","given","additional","prefix","suffix"},
+	vcard.PropAdr: {"pobox","ext","street","locality","region","code","country"},
+	vcard.PropGender: {"sex","identity"},
+	vcard.PropClientPidmap: {"sourceid","uri"},
+}
+
+/*
+properties whose value elements are a list,the value elements of other
+properties are components,e.g. ORG
+ */
+var listProps = map[string]bool{
+	vcard.PropNickName: true,
+	vcard.PropCategories: true,
+}
+
+/*
+value type of params,other params are text
+ */
+var paramTypes = map[string]string{
+	vcard.ParamPref: "integer",
+	vcard.ParamLanguage: "language-tag",
+	vcard.ParamGEO: "uri",
+}
+
+type Encoder struct {
+	writer io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer:w}
+}
+
+/*
+write a xCard document of a single card
+ */
+func (ec *Encoder) Encode(c vcard.Card) error {
+	return ec.EncodeAll([]vcard.Card{c})
+}
+
+/*
+write a xCard document of the cards with a single Write
+ */
+func (ec *Encoder) EncodeAll(cards []vcard.Card) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<vcards xmlns="`+Namespace+`">`)
+	for _,c := range cards{
+		if err := writeCard(&buf,c);err != nil{
+			return err
+		}
+	}
+	buf.WriteString("</vcards>\n")
+	_,err := ec.writer.Write(buf.Bytes())
+	return err
+}
+
+func writeCard(buf *bytes.Buffer,c vcard.Card) error {
+	buf.WriteString("<vcard>")
+	props := c.Properties()
+	written := make(map[string]bool)
+	for _,p := range props{
+		if strings.EqualFold(p.Name,vcard.PropVersion){
+			//xCard is always 4.0
+			continue
+		}
+		if p.Group == ""{
+			if err := writeProperty(buf,p);err != nil{
+				return err
+			}
+			continue
+		}
+		if written[p.Group]{
+			continue
+		}
+		//all properties of a group are written in the group element
+		written[p.Group] = true
+		buf.WriteString(`<group name="`)
+		xml.EscapeText(buf,[]byte(p.Group))
+		buf.WriteString(`">`)
+		for _,gp := range props{
+			if gp.Group != p.Group{
+				continue
+			}
+			if err := writeProperty(buf,gp);err != nil{
+				return err
+			}
+		}
+		buf.WriteString("</group>")
+	}
+	buf.WriteString("</vcard>")
+	return nil
+}
+
+func writeProperty(buf *bytes.Buffer,p *vcard.Property) error {
+	name := strings.ToUpper(p.Name)
+	if name == vcard.PropXML{
+		//XML property hold an element of another namespace,it is written
+		//as is only when it is a single element
+		v := p.GetValueText()
+		if err := checkElement(v);err != nil{
+			return err
+		}
+		buf.WriteString(v)
+		return nil
+	}
+	elem := strings.ToLower(name)
+	buf.WriteString("<"+elem+">")
+	typ := p.ValueType()
+	if len(p.Params) > 0{
+		writeParams(buf,p.Params)
+	}
+	if comps,ok := components[name];ok{
+		for i,comp := range comps{
+			var vals []string
+			if i < len(p.Value){
+				vals = p.Value[i]
+			}
+			if len(vals) == 0{
+				writeElement(buf,comp,"")
+			}
+			for _,v := range vals{
+				writeElement(buf,comp,v)
+			}
+		}
+	}else if listProps[name] && len(p.Value) == 1{
+		for _,v := range p.Value[0]{
+			writeElement(buf,typ,v)
+		}
+	}else if name == vcard.PropOrg{
+		//the organization and its units
+		for _,vals := range p.Value{
+			writeElement(buf,typ,strings.Join(vals,","))
+		}
+	}else{
+		//a single value,e.g. a data: URI or a tel: URI with ;ext=
+		writeElement(buf,typ,p.GetValueText())
+	}
+	buf.WriteString("</"+elem+">")
+	return nil
+}
+
+/*
+check that s is exactly one well-formed element,
+e.g. </vcard><x> would break the document
+ */
+func checkElement(s string) error {
+	d := xml.NewDecoder(strings.NewReader(s))
+	depth,elems := 0,0
+	for{
+		tok,err := d.Token()
+		if err == io.EOF{
+			break
+		}
+		if err != nil{
+			return fmt.Errorf("xcard:XML property %q:%v",s,err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0{
+				elems++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0{
+				return fmt.Errorf("xcard:XML property %q:text outside of the element",s)
+			}
+		case xml.ProcInst,xml.Directive:
+			if depth == 0{
+				return fmt.Errorf("xcard:XML property %q:not a single element",s)
+			}
+		}
+	}
+	if elems != 1 || depth != 0{
+		return fmt.Errorf("xcard:XML property %q:not a single element",s)
+	}
+	return nil
+}
+
+func writeParams(buf *bytes.Buffer,params map[string][]string)  {
+	var keys []string
+	for _,k := range sortedKeys(params){
+		//VALUE is the name of the value element
+		if !strings.EqualFold(k,vcard.ParamValue){
+			keys = append(keys,k)
+		}
+	}
+	if len(keys) == 0{
+		return
+	}
+	buf.WriteString("<parameters>")
+	for _,k := range keys{
+		name := strings.ToUpper(k)
+		typ := paramTypes[name]
+		if typ == ""{
+			typ = "text"
+		}
+		elem := strings.ToLower(name)
+		buf.WriteString("<"+elem+">")
+		for _,v := range params[k]{
+			writeElement(buf,typ,v)
+		}
+		buf.WriteString("</"+elem+">")
+	}
+	buf.WriteString("</parameters>")
+}
+
+func writeElement(buf *bytes.Buffer,name,text string)  {
+	if text == ""{
+		buf.WriteString("<"+name+"/>")
+		return
+	}
+	buf.WriteString("<"+name+">")
+	xml.EscapeText(buf,[]byte(text))
+	buf.WriteString("</"+name+">")
+}
+
+type Decoder struct {
+	d *xml.Decoder
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d:xml.NewDecoder(r)}
+}
+
+/*
+decode the next vcard element,return io.EOF when there is no more card.
+elements of other namespaces are kept in XML properties
+ */
+func (dc *Decoder) Decode() (vcard.Card,error) {
+	for{
+		tok,err := dc.d.Token()
+		if err != nil{
+			return nil,err
+		}
+		if se,ok := tok.(xml.StartElement);ok && se.Name.Space == Namespace && se.Name.Local == "vcard"{
+			return dc.decodeCard()
+		}
+	}
+}
+
+func (dc *Decoder) decodeCard() (vcard.Card,error) {
+	c := make(vcard.Card)
+	c.Set(vcard.PropVersion,&vcard.Property{Name:vcard.PropVersion,Value:[][]string{{"4.0"}}})
+	group := ""
+	for{
+		tok,err := dc.d.Token()
+		if err != nil{
+			return nil,unexpectedEOF(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == Namespace && t.Name.Local == "group"{
+				group = attr(t,"name")
+				if group == ""{
+					return nil,errors.New("xcard:group without name")
+				}
+				continue
+			}
+			p,err := dc.decodeProperty(t)
+			if err != nil{
+				return nil,err
+			}
+			p.Group = group
+			c.Add(p.Name,p)
+		case xml.EndElement:
+			if t.Name.Local == "group"{
+				group = ""
+			}else{
+				return c,nil
+			}
+		}
+	}
+}
+
+func (dc *Decoder) decodeProperty(se xml.StartElement) (*vcard.Property,error) {
+	if se.Name.Space != Namespace{
+		raw,err := dc.rawXML(se)
+		if err != nil{
+			return nil,err
+		}
+		return &vcard.Property{Name:vcard.PropXML,Value:[][]string{{raw}}},nil
+	}
+	p := &vcard.Property{Name:strings.ToUpper(se.Name.Local)}
+	comps := components[p.Name]
+	if comps != nil{
+		p.Value = make([][]string,len(comps))
+	}
+	typ := ""
+	var vals []string
+	for{
+		tok,err := dc.d.Token()
+		if err != nil{
+			return nil,unexpectedEOF(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "parameters"{
+				if err := dc.decodeParams(p);err != nil{
+					return nil,err
+				}
+				continue
+			}
+			text,err := dc.text()
+			if err != nil{
+				return nil,err
+			}
+			if i := indexOf(comps,t.Name.Local);i >= 0{
+				p.Value[i] = append(p.Value[i],text)
+				continue
+			}
+			typ = t.Name.Local
+			vals = append(vals,text)
+		case xml.EndElement:
+			for i := range p.Value{
+				if len(p.Value[i]) == 0{
+					p.Value[i] = []string{""}
+				}
+			}
+			if comps == nil{
+				if listProps[p.Name]{
+					p.Value = [][]string{vals}
+				}else if len(vals) == 1 && typ != "text" && typ != "unknown"{
+					//split as the text decoder do,e.g. a data: URI
+					p.SetValueText(vals[0])
+				}else{
+					//a comma in the text of a value element is not a separator
+					for _,v := range vals{
+						p.Value = append(p.Value,[]string{v})
+					}
+				}
+			}
+			if typ != "" && typ != p.ValueType() && typ != "unknown"{
+				p.SetParam(vcard.ParamValue,typ)
+			}
+			return p,nil
+		}
+	}
+}
+
+func (dc *Decoder) decodeParams(p *vcard.Property) error {
+	for{
+		tok,err := dc.d.Token()
+		if err != nil{
+			return unexpectedEOF(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToUpper(t.Name.Local)
+			vals,err := dc.values()
+			if err != nil{
+				return err
+			}
+			for _,v := range vals{
+				p.AddParam(name,v)
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+/*
+read the text of the value elements until the end of the current element
+ */
+func (dc *Decoder) values() ([]string,error) {
+	var vals []string
+	for{
+		tok,err := dc.d.Token()
+		if err != nil{
+			return nil,unexpectedEOF(err)
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			text,err := dc.text()
+			if err != nil{
+				return nil,err
+			}
+			vals = append(vals,text)
+		case xml.EndElement:
+			return vals,nil
+		}
+	}
+}
+
+/*
+read the text until the end of the current element
+ */
+func (dc *Decoder) text() (string,error) {
+	var buf bytes.Buffer
+	depth := 1
+	for depth > 0{
+		tok,err := dc.d.Token()
+		if err != nil{
+			return "",unexpectedEOF(err)
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			buf.Write(t)
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return buf.String(),nil
+}
+
+/*
+serialize the element started by se and its content back to XML text
+ */
+func (dc *Decoder) rawXML(se xml.StartElement) (string,error) {
+	var buf bytes.Buffer
+	spaces := []string{""}
+	//prefixes of the attribute namespaces in scope,by namespace
+	prefixes := []map[string]string{{}}
+	tok := xml.Token(se)
+	for{
+		switch t := tok.(type) {
+		case xml.StartElement:
+			buf.WriteString("<"+t.Name.Local)
+			if parent := spaces[len(spaces)-1];t.Name.Space != parent{
+				buf.WriteString(` xmlns="`)
+				xml.EscapeText(&buf,[]byte(t.Name.Space))
+				buf.WriteString(`"`)
+			}
+			scope := make(map[string]string)
+			for k,v := range prefixes[len(prefixes)-1]{
+				scope[k] = v
+			}
+			for _,a := range t.Attr{
+				if a.Name.Space == "xmlns"{
+					scope[a.Value] = a.Name.Local
+					writeAttr(&buf,"xmlns:"+a.Name.Local,a.Value)
+				}
+			}
+			for _,a := range t.Attr{
+				switch{
+				case a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns":
+					continue
+				case a.Name.Space == "":
+					writeAttr(&buf,a.Name.Local,a.Value)
+				case a.Name.Space == xmlSpace:
+					//the xml prefix is bound without a declaration,e.g. xml:lang
+					writeAttr(&buf,"xml:"+a.Name.Local,a.Value)
+				default:
+					prefix,ok := scope[a.Name.Space]
+					if !ok{
+						//the decoder keeps the namespace of an undeclared prefix
+						prefix = fmt.Sprintf("ns%d",len(scope)+1)
+						scope[a.Name.Space] = prefix
+						writeAttr(&buf,"xmlns:"+prefix,a.Name.Space)
+					}
+					writeAttr(&buf,prefix+":"+a.Name.Local,a.Value)
+				}
+			}
+			buf.WriteString(">")
+			spaces = append(spaces,t.Name.Space)
+			prefixes = append(prefixes,scope)
+		case xml.EndElement:
+			buf.WriteString("</"+t.Name.Local+">")
+			spaces = spaces[:len(spaces)-1]
+			prefixes = prefixes[:len(prefixes)-1]
+			if len(spaces) == 1{
+				return buf.String(),nil
+			}
+		case xml.CharData:
+			xml.EscapeText(&buf,t)
+		}
+		var err error
+		if tok,err = dc.d.Token();err != nil{
+			return "",unexpectedEOF(err)
+		}
+	}
+}
+
+func writeAttr(buf *bytes.Buffer,name,value string)  {
+	buf.WriteString(" "+name+`="`)
+	xml.EscapeText(buf,[]byte(value))
+	buf.WriteString(`"`)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF{
+		return fmt.Errorf("xcard:%v",io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+func attr(se xml.StartElement,name string) string {
+	for _,a := range se.Attr{
+		if a.Name.Local == name{
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func indexOf(list []string,s string) int {
+	for i,e := range list{
+		if e == s{
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string,0,len(m))
+	for k := range m{
+		keys = append(keys,k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,222 @@
+package xcard
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	vcard "github.com/ScottAI/go-vcard"
+)
+
+func TestEncoder(t *testing.T) {
+	card := vcard.Card{
+		vcard.PropVersion: {{Value: [][]string{{"4.0"}}}},
+		vcard.PropFN:      {{Value: [][]string{{"Joe & Bloggs"}}}},
+		vcard.PropN:       {{Value: [][]string{{"Bloggs"}, {"Joe"}, {""}, {""}, {""}}}},
+		vcard.PropTel: {{
+			Group:  "item1",
+			Params: map[string][]string{vcard.ParamType: {"home"}, vcard.ParamPref: {"1"}},
+			Value:  [][]string{{"tel:+1-555-555-5555"}},
+		}},
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding xCard, got:", err)
+	}
+	out := b.String()
+	expected := []string{
+		`<vcards xmlns="` + Namespace + `"><vcard>`,
+		"<fn><text>Joe &amp; Bloggs</text></fn>",
+		"<n><surname>Bloggs</surname><given>Joe</given><additional/><prefix/><suffix/></n>",
+		`<group name="item1"><tel><parameters><pref><integer>1</integer></pref><type><text>home</text></type></parameters><text>tel:+1-555-555-5555</text></tel></group>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected xCard to contain %q but got:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "<version>") {
+		t.Errorf("Expected VERSION not to be written but got:\n%s", out)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<vcards xmlns="urn:ietf:params:xml:ns:vcard-4.0">
+  <vcard>
+    <fn><text>Joe Bloggs</text></fn>
+    <n><surname>Bloggs</surname><given>Joe</given><additional/><prefix>Dr.</prefix><suffix/></n>
+    <nickname><text>Joey</text><text>JB</text></nickname>
+    <group name="item1">
+      <email><parameters><type><text>work</text></type></parameters><text>joe@example.com</text></email>
+    </group>
+    <bday><date>19850412</date></bday>
+    <a xmlns="http://www.w3.org/1999/xhtml" href="http://example.com/">Joe</a>
+  </vcard>
+  <vcard>
+    <fn><text>Jane</text></fn>
+  </vcard>
+</vcards>`
+
+	dec := NewDecoder(strings.NewReader(input))
+	card, err := dec.Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding xCard, got:", err)
+	}
+	if v := card.Get(vcard.PropVersion).GetValueFirstText(); v != "4.0" {
+		t.Errorf("Expected VERSION 4.0 but got %q", v)
+	}
+	if v := card.Get(vcard.PropFN).GetValueFirstText(); v != "Joe Bloggs" {
+		t.Errorf("Expected FN Joe Bloggs but got %q", v)
+	}
+	n := [][]string{{"Bloggs"}, {"Joe"}, {""}, {"Dr."}, {""}}
+	if v := card.Get(vcard.PropN).Value; !reflect.DeepEqual(v, n) {
+		t.Errorf("Expected N %q but got %q", n, v)
+	}
+	if v := card.Get(vcard.PropNickName).Value; !reflect.DeepEqual(v, [][]string{{"Joey", "JB"}}) {
+		t.Errorf("Expected NICKNAME list but got %q", v)
+	}
+	email := card.Get(vcard.PropEmail)
+	if email.Group != "item1" || !email.IsHasType("work") {
+		t.Errorf("Expected grouped EMAIL with TYPE=work but got %+v", email)
+	}
+	if v := card.Get(vcard.PropBday).GetFirstParamVal(vcard.ParamValue); v != "date" {
+		t.Errorf("Expected BDAY VALUE=date but got %q", v)
+	}
+	xml := `<a xmlns="http://www.w3.org/1999/xhtml" href="http://example.com/">Joe</a>`
+	if v := card.Get(vcard.PropXML).GetValueFirstText(); v != xml {
+		t.Errorf("Expected unknown element to be kept as XML %q but got %q", xml, v)
+	}
+
+	if card, err = dec.Decode(); err != nil {
+		t.Fatal("Expected no error when decoding second card, got:", err)
+	}
+	if v := card.Get(vcard.PropFN).GetValueFirstText(); v != "Jane" {
+		t.Errorf("Expected FN Jane but got %q", v)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of document but got %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	card := vcard.Card{
+		vcard.PropVersion: {{Name: vcard.PropVersion, Value: [][]string{{"4.0"}}}},
+		vcard.PropFN:      {{Name: vcard.PropFN, Value: [][]string{{"Joe Bloggs"}}}},
+		vcard.PropAdr: {{
+			Name:   vcard.PropAdr,
+			Params: map[string][]string{vcard.ParamType: {"work"}},
+			Value:  [][]string{{""}, {""}, {"123 Main St"}, {"Springfield"}, {"IL"}, {"12345"}, {"USA"}},
+		}},
+		vcard.PropOrg:        {{Name: vcard.PropOrg, Value: [][]string{{"ABC, Inc."}, {"North American Division"}}}},
+		vcard.PropCategories: {{Name: vcard.PropCategories, Value: [][]string{{"work", "friends"}}}},
+		vcard.PropXML:        {{Name: vcard.PropXML, Value: [][]string{{`<x xmlns="urn:example">y</x>`}}}},
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding xCard, got:", err)
+	}
+	got, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding xCard, got:", err)
+	}
+	if !reflect.DeepEqual(got, card) {
+		t.Errorf("Expected round-tripped card\n%v\nbut got\n%v", card, got)
+	}
+}
+
+func TestRoundTrip_singleValues(t *testing.T) {
+	input := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:J. Doe\r\n" +
+		"GEO:geo:37.386013,-122.082932\r\n" +
+		"PHOTO:data:image/png;base64,iVBORw0KGgo=\r\n" +
+		"TEL;VALUE=uri:tel:+1-555-555-5555;ext=5555\r\n" +
+		"NOTE:a\\, b\\; c\r\n" +
+		"END:VCARD\r\n"
+	card, err := vcard.NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding xCard, got:", err)
+	}
+	for _, e := range []string{
+		"<geo><uri>geo:37.386013,-122.082932</uri></geo>",
+		"<photo><uri>data:image/png;base64,iVBORw0KGgo=</uri></photo>",
+		"<tel><uri>tel:+1-555-555-5555;ext=5555</uri></tel>",
+		"<note><text>a, b; c</text></note>",
+	} {
+		if !strings.Contains(b.String(), e) {
+			t.Errorf("Expected xCard to contain %q but got:\n%s", e, b.String())
+		}
+	}
+
+	got, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding xCard, got:", err)
+	}
+	var text bytes.Buffer
+	if err := vcard.NewEncoder(&text).Encode(got); err != nil {
+		t.Fatal("Expected no error when encoding card, got:", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(input, "\r\n"), "\r\n") {
+		if !strings.Contains(text.String(), line+"\r\n") {
+			t.Errorf("Expected %q after an xCard round trip but got\n%s", line, text.String())
+		}
+	}
+}
+
+func TestEncoder_invalidXMLProperty(t *testing.T) {
+	for _, v := range []string{
+		`</vcard></vcards><x>`,
+		`<a>b</a><c/>`,
+		`text <a/>`,
+		`<a>`,
+		``,
+	} {
+		card := vcard.Card{
+			vcard.PropFN:  {{Name: vcard.PropFN, Value: [][]string{{"Joe"}}}},
+			vcard.PropXML: {{Name: vcard.PropXML, Value: [][]string{{v}}}},
+		}
+		var b bytes.Buffer
+		if err := NewEncoder(&b).Encode(card); err == nil || !strings.HasPrefix(err.Error(), "xcard:") {
+			t.Errorf("Expected an error when encoding XML property %q but got %v", v, err)
+		}
+		if b.Len() != 0 {
+			t.Errorf("Expected nothing to be written for XML property %q but got %q", v, b.String())
+		}
+	}
+}
+
+func TestDecoder_namespacedAttributes(t *testing.T) {
+	input := `<vcards xmlns="urn:ietf:params:xml:ns:vcard-4.0"><vcard>` +
+		`<fn><text>Joe</text></fn>` +
+		`<a xmlns="urn:example" xmlns:e="urn:ext" xml:lang="fr" e:kind="x"><b e:kind="y"/></a>` +
+		`</vcard></vcards>`
+	card, err := NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding xCard, got:", err)
+	}
+	v := card.Get(vcard.PropXML).GetValueFirstText()
+	expected := `<a xmlns="urn:example" xmlns:e="urn:ext" xml:lang="fr" e:kind="x"><b e:kind="y"></b></a>`
+	if v != expected {
+		t.Errorf("Expected XML property %q but got %q", expected, v)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(card); err != nil {
+		t.Fatal("Expected no error when encoding xCard, got:", err)
+	}
+	got, err := NewDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal("Expected no error when decoding xCard, got:", err)
+	}
+	if v := got.Get(vcard.PropXML).GetValueFirstText(); v != expected {
+		t.Errorf("Expected XML property %q after a round trip but got %q", expected, v)
+	}
+}